@@ -0,0 +1,256 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// CodeBuildRequest 定义编译检查请求结构
+type CodeBuildRequest struct {
+	Files             []string `json:"files" description:"参考文件列表（可选）。checkOnlyChanges=false 时检查这些文件所在的包"`
+	ProjectPath       string   `json:"projectPath" description:"项目根目录（可选，优先作为检测起点）"`
+	CheckOnlyChanges  bool     `json:"checkOnlyChanges" description:"是否只检查变更文件所在的包（默认true）" default:"true"`
+	IncludeTests      bool     `json:"includeTests" description:"是否同时通过 go vet 类型检查测试代码（默认true）" default:"true"`
+	IncludeDependents bool     `json:"includeDependents" description:"是否同时检查依赖变更包的其他包（默认false）"`
+	DependentsDepth   int      `json:"dependentsDepth" description:"依赖包展开层级：1为只包含直接导入方（默认），N为传递N层，0为不限层级"`
+	TrunkBranchesArg
+}
+
+// BuildResult 表示编译检查结果
+type BuildResult struct {
+	Passed     bool               `json:"Passed"`
+	Issues     []Issue            `json:"Issues"`
+	Dependents []DependentPackage `json:"Dependents,omitempty"`
+	Scope      *ScopeInfo         `json:"Scope,omitempty"`
+}
+
+// compilerErrorRegex 匹配编译器错误行，如 "a/a.go:4:17: undefined: x"（列号可选）
+var compilerErrorRegex = regexp.MustCompile(`^(.+?\.go):(\d+)(?::(\d+))?: (.*)$`)
+
+// parseCompilerOutput 将 go build / go vet 的输出解析为 Issue 列表
+// onlyVetTypeErrors=true 时只保留 go vet 中以 "vet: " 开头的类型检查错误，忽略普通的 vet 诊断
+func parseCompilerOutput(projectRoot, output, linter string, onlyVetTypeErrors bool) []Issue {
+	var issues []Issue
+	var pkg string
+	var other []string
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		// "# pkg" 或 "# [pkg]" 标记后续错误所属的包
+		if strings.HasPrefix(line, "# ") {
+			if !strings.HasPrefix(line, "# [") {
+				pkg = strings.TrimPrefix(line, "# ")
+			}
+			continue
+		}
+		// 以制表符开头的行是上一条错误的补充说明（如 have/want）
+		if strings.HasPrefix(line, "\t") && len(issues) > 0 {
+			issues[len(issues)-1].Text += "\n" + strings.TrimSpace(line)
+			continue
+		}
+
+		text := line
+		isVetTypeError := strings.HasPrefix(line, "vet: ")
+		if isVetTypeError {
+			text = strings.TrimPrefix(line, "vet: ")
+		} else if onlyVetTypeErrors {
+			continue
+		}
+
+		m := compilerErrorRegex.FindStringSubmatch(text)
+		if m == nil {
+			other = append(other, line)
+			continue
+		}
+		filename := m[1]
+		if !filepath.IsAbs(filename) {
+			filename = filepath.Join(projectRoot, filename)
+		}
+		lineNo, _ := strconv.Atoi(m[2])
+		column, _ := strconv.Atoi(m[3])
+		msg := m[4]
+		if pkg != "" {
+			msg = fmt.Sprintf("%s（包: %s）", msg, pkg)
+		}
+		issues = append(issues, Issue{
+			FromLinter: linter,
+			Text:       msg,
+			Severity:   "error",
+			Pos:        Pos{Filename: filepath.Clean(filename), Line: lineNo, Column: column},
+		})
+	}
+
+	// 无法定位到文件的错误（如模块解析失败）作为一条系统级问题返回
+	if len(issues) == 0 && len(other) > 0 {
+		issues = append(issues, Issue{
+			FromLinter: linter,
+			Text:       strings.Join(other, "\n"),
+			Severity:   "error",
+			Pos:        Pos{Filename: projectRoot},
+		})
+	}
+	return issues
+}
+
+// runGoCommand 在项目根目录执行 go 子命令，返回合并输出以及是否成功
+// env 为执行环境变量，交叉平台检查时包含 GOOS/GOARCH
+func runGoCommand(ctx context.Context, projectRoot string, args []string, env []string) (string, bool) {
+	log.Printf("执行命令: go %v", args)
+	log.Printf("命令执行目录: %s", projectRoot)
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Dir = projectRoot
+	cmd.Env = env
+	output, err := cmd.CombinedOutput()
+	log.Printf("命令输出长度: %d，执行错误: %v", len(output), err)
+	return string(output), err == nil
+}
+
+// runGoBuild 编译指定的包并解析编译错误；includeTests=true 时在编译通过后用 go vet 类型检查测试代码
+// 只含测试文件的目录无法 go build，仅在 includeTests=true 时由 go vet 检查；bc 指定构建标签与目标平台
+func runGoBuild(ctx context.Context, projectRoot string, packages []string, vendorMode bool, includeTests bool, bc BuildConfig) []Issue {
+	var buildable []string
+	for _, pkg := range packages {
+		if isTestOnlyDir(filepath.Join(projectRoot, pkg)) {
+			log.Printf("包 %s 只包含测试文件，跳过 go build", pkg)
+			continue
+		}
+		buildable = append(buildable, pkg)
+	}
+	if len(buildable) > 0 {
+		buildArgs := []string{"build", "-o", os.DevNull}
+		if vendorMode {
+			buildArgs = append(buildArgs, "-mod=vendor")
+		}
+		buildArgs = append(buildArgs, bc.goTagsArgs()...)
+		buildArgs = append(buildArgs, buildable...)
+		output, ok := runGoCommand(ctx, projectRoot, buildArgs, bc.Env())
+		if !ok {
+			issues := parseCompilerOutput(projectRoot, output, "go build", false)
+			log.Printf("项目 %s 编译失败，解析到 %d 个编译错误", projectRoot, len(issues))
+			return issues
+		}
+	}
+	if !includeTests {
+		return nil
+	}
+
+	vetArgs := []string{"vet"}
+	if vendorMode {
+		vetArgs = append(vetArgs, "-mod=vendor")
+	}
+	vetArgs = append(vetArgs, bc.goTagsArgs()...)
+	vetArgs = append(vetArgs, packages...)
+	output, ok := runGoCommand(ctx, projectRoot, vetArgs, bc.Env())
+	if ok {
+		return nil
+	}
+	issues := parseCompilerOutput(projectRoot, output, "go vet", true)
+	log.Printf("项目 %s 测试代码类型检查解析到 %d 个错误", projectRoot, len(issues))
+	return issues
+}
+
+// checkBuildBeforeLint 在代码检查前对各项目的包做编译检查，返回全部编译错误
+// 代码无法编译时 golangci-lint 只会输出难以解析的 typecheck 信息，应直接返回编译错误
+func checkBuildBeforeLint(ctx context.Context, projectPackages map[string][]string, bc BuildConfig) []Issue {
+	var issues []Issue
+	for projectRoot, packages := range projectPackages {
+		vendorMode := autoDetectVendorMode(projectRoot)
+		issues = append(issues, runGoBuild(ctx, projectRoot, packages, vendorMode, true, bc)...)
+	}
+	return issues
+}
+
+// handleCodeBuildRequest 处理编译检查请求
+func handleCodeBuildRequest(ctx context.Context, req mcp.CallToolRequest) (result *mcp.CallToolResult, err error) {
+	// 捕获任何 panic 并转换为错误返回
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("发生 panic: %v", r)
+			result = buildErrorResult(fmt.Sprintf("内部错误: %v", r))
+			err = nil
+		}
+	}()
+
+	log.Printf("收到编译检查请求: name=%s args=%v", req.Params.Name, req.Params.Arguments)
+
+	var buildReq CodeBuildRequest
+	if req.Params.Arguments == nil {
+		req.Params.Arguments = map[string]interface{}{}
+	}
+	cfg := applyToolDefaults("code_build", req.Params.Arguments)
+	if !cfg.backendEnabled(backendGoBuild) {
+		return backendDisabledResult(backendGoBuild, cfg), nil
+	}
+	argsBytes, _ := json.Marshal(req.Params.Arguments)
+	if err := json.Unmarshal(argsBytes, &buildReq); err != nil {
+		return buildErrorResult(fmt.Sprintf("无效的请求参数: %v", err)), nil
+	}
+	if _, exists := req.Params.Arguments["checkOnlyChanges"]; !exists {
+		buildReq.CheckOnlyChanges = true
+	}
+	if _, exists := req.Params.Arguments["includeTests"]; !exists {
+		buildReq.IncludeTests = true
+	}
+	if _, exists := req.Params.Arguments["dependentsDepth"]; !exists {
+		buildReq.DependentsDepth = 1
+	}
+
+	baseDir, err := resolveBaseDir(buildReq.ProjectPath, buildReq.Files)
+	if err != nil {
+		return buildPathErrorResult(err), nil
+	}
+	log.Printf("编译检查起点目录: %s", baseDir)
+
+	projectPackages, scope, err := resolveScopePackages(baseDir, buildReq.ProjectPath, buildReq.Files, buildReq.CheckOnlyChanges, ScopeOptions{TrunkBranches: buildReq.TrunkBranches, Config: cfg})
+	if err != nil {
+		return buildErrorResult(err.Error()), nil
+	}
+
+	buildResult := &BuildResult{Passed: true, Issues: make([]Issue, 0), Scope: scope}
+	projectRoots := make([]string, 0, len(projectPackages))
+	for projectRoot := range projectPackages {
+		projectRoots = append(projectRoots, projectRoot)
+	}
+	sort.Strings(projectRoots)
+
+	for _, projectRoot := range projectRoots {
+		packages := projectPackages[projectRoot]
+		vendorMode := autoDetectVendorMode(projectRoot)
+		if buildReq.IncludeDependents {
+			dependents, err := expandDependents(ctx, projectRoot, packages, buildReq.DependentsDepth, vendorMode)
+			if err != nil {
+				log.Printf("查找依赖包失败（项目: %s）: %v", projectRoot, err)
+			} else {
+				for _, dep := range dependents {
+					packages = append(packages, dep.Package)
+				}
+				buildResult.Dependents = append(buildResult.Dependents, dependents...)
+			}
+		}
+
+		issues := runGoBuild(ctx, projectRoot, packages, vendorMode, buildReq.IncludeTests, BuildConfig{})
+		if len(issues) > 0 {
+			buildResult.Passed = false
+			buildResult.Issues = append(buildResult.Issues, issues...)
+		}
+	}
+
+	return buildToolResult(buildResult, cfg), nil
+}
@@ -0,0 +1,424 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// renameSimilarityThreshold 未通过 git mv 重命名（删除+新增未跟踪文件）时判定为重命名的最低相似度，与 git 默认值一致
+const renameSimilarityThreshold = 50
+
+// ChangedFile 表示一个变更的 Go 文件
+type ChangedFile struct {
+	Path       string `json:"Path"`              // 当前绝对路径（删除时为原路径）
+	OldPath    string `json:"OldPath,omitempty"` // 重命名/复制前的绝对路径
+	Status     string `json:"Status"`            // A 新增、M 修改、D 删除、R 重命名、C 复制
+	Similarity int    `json:"Similarity,omitempty"`
+
+	// 重命名/复制文件相对原文件的行变更，用于只报告真正修改过的行
+	lineMap *fileLineMap
+}
+
+// fileLineMap 记录重命名/复制后的文件中新增或修改过的行
+type fileLineMap struct {
+	changedLines map[int]bool
+}
+
+// ChangeSet 表示一次变更检测的结果
+type ChangeSet struct {
+	ProjectRoot string
+	Repos       []RepoBase    // 参与检测的仓库（起点所在仓库在前）
+	Files       []ChangedFile // 仍然存在的变更文件
+	Deleted     []ChangedFile // 已删除的文件
+
+	SkippedGenerated []string // 因生成代码而跳过的变更文件
+}
+
+// Paths 返回仍然存在的变更文件路径
+func (cs *ChangeSet) Paths() []string {
+	paths := make([]string, 0, len(cs.Files))
+	for _, f := range cs.Files {
+		paths = append(paths, f.Path)
+	}
+	return paths
+}
+
+// Scope 返回检查范围说明：起点所在仓库的基准，以及子模块/嵌套仓库各自的基准
+func (cs *ChangeSet) Scope() *ScopeInfo {
+	if len(cs.Repos) == 0 {
+		return nil
+	}
+	scope := &ScopeInfo{RepoBase: cs.Repos[0], Repos: cs.Repos[1:]}
+	if len(scope.Repos) > 0 {
+		scope.Explanation += fmt.Sprintf("；另检测 %d 个子模块/嵌套仓库，各自独立确定基准（见 Repos）", len(scope.Repos))
+	}
+	return scope
+}
+
+// DeletedPackages 返回有文件被删除（含重命名移出目录）、但目录中仍有 Go 文件的包，按项目根目录分组
+// 删除文件可能导致包内其他文件引用失效，这些包需要重新检查
+func (cs *ChangeSet) DeletedPackages() map[string][]string {
+	removed := make([]string, 0, len(cs.Deleted))
+	for _, f := range cs.Deleted {
+		removed = append(removed, f.Path)
+	}
+	for _, f := range cs.Files {
+		if f.Status == "R" && f.OldPath != "" && filepath.Dir(f.OldPath) != filepath.Dir(f.Path) {
+			removed = append(removed, f.OldPath)
+		}
+	}
+
+	projectPackages := make(map[string]map[string]bool)
+	for _, path := range removed {
+		dir := filepath.Dir(path)
+		matches, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+		if len(matches) == 0 {
+			continue
+		}
+		projectRoot, err := getProjectRootFromFile(path)
+		if err != nil {
+			continue
+		}
+		rel, err := toRelPackagePath(projectRoot, dir)
+		if err != nil {
+			continue
+		}
+		if projectPackages[projectRoot] == nil {
+			projectPackages[projectRoot] = make(map[string]bool)
+		}
+		projectPackages[projectRoot][rel] = true
+	}
+
+	result := make(map[string][]string)
+	for projectRoot, packages := range projectPackages {
+		for pkg := range packages {
+			result[projectRoot] = append(result[projectRoot], pkg)
+		}
+		sort.Strings(result[projectRoot])
+	}
+	return result
+}
+
+// FilterIssues 过滤重命名/复制文件中位于未修改行上的问题，避免改名后整个文件被当作新代码检查
+func (cs *ChangeSet) FilterIssues(projectRoot string, issues []Issue) []Issue {
+	renamed := make(map[string]*fileLineMap)
+	for _, f := range cs.Files {
+		if f.lineMap != nil {
+			renamed[f.Path] = f.lineMap
+		}
+	}
+	if len(renamed) == 0 {
+		return issues
+	}
+
+	filtered := make([]Issue, 0, len(issues))
+	for _, issue := range issues {
+		lm, ok := renamed[issueAbsPath(projectRoot, issue)]
+		if ok && issue.Pos.Line > 0 && !lm.changedLines[issue.Pos.Line] {
+			continue
+		}
+		filtered = append(filtered, issue)
+	}
+	if dropped := len(issues) - len(filtered); dropped > 0 {
+		log.Printf("过滤重命名文件中未修改行上的 %d 个问题", dropped)
+	}
+	return filtered
+}
+
+// lineSimilarity 基于逐行差异计算两个文件内容的相似度（0-100）
+func lineSimilarity(a, b []string) int {
+	if len(a)+len(b) == 0 {
+		return 100
+	}
+	common := 0
+	for _, op := range diffLines(a, b) {
+		if op.kind == ' ' {
+			common++
+		}
+	}
+	return common * 2 * 100 / (len(a) + len(b))
+}
+
+// buildLineMap 对比旧内容与新内容，记录新内容中新增或修改过的行
+func buildLineMap(oldLines, newLines []string) *fileLineMap {
+	lm := &fileLineMap{changedLines: make(map[int]bool)}
+	for _, op := range diffLines(oldLines, newLines) {
+		if op.kind == '+' {
+			lm.changedLines[op.bIdx+1] = true
+		}
+	}
+	return lm
+}
+
+// RepoBase 记录变更检测涉及的单个 Git 仓库（主仓库、子模块或嵌套仓库）及其基准提交
+type RepoBase struct {
+	Root          string   `json:"Root"`
+	BaseCommit    string   `json:"BaseCommit"` // 为空表示与 HEAD 比较（仅工作区变更）
+	Strategy      string   `json:"Strategy"`
+	Trunk         string   `json:"Trunk,omitempty"` // 选中的最近主干分支
+	TrunkPatterns []string `json:"TrunkPatterns,omitempty"`
+	PatternSource string   `json:"PatternSource,omitempty"` // 主干模式来源：工具参数、配置文件或默认值
+	Explanation   string   `json:"Explanation"`
+	Submodule     bool     `json:"Submodule,omitempty"`
+}
+
+// getChangeSet 检测变更的 Go 文件（工作区 + 提交范围），识别重命名、复制和删除
+// 起点所在仓库之外，还会检测起点目录下的子模块和嵌套仓库，每个仓库独立确定基准提交
+func getChangeSet(projectRoot string, opts ScopeOptions) (*ChangeSet, error) {
+	mainRepo := newGitRepo(projectRoot)
+	if _, err := mainRepo.TopLevel(); err != nil {
+		return nil, fmt.Errorf("不是Git仓库: %s", projectRoot)
+	}
+
+	cs := &ChangeSet{ProjectRoot: projectRoot}
+	repos := append([]nestedRepo{{root: projectRoot}}, discoverNestedRepos(mainRepo)...)
+	for i, repo := range repos {
+		gitRepo := mainRepo
+		if i > 0 {
+			gitRepo = newGitRepo(repo.root)
+		}
+		files, deleted, base, err := collectRepoChanges(gitRepo, opts)
+		if err != nil {
+			log.Printf("仓库 %s 变更检测失败: %v", repo.root, err)
+			continue
+		}
+		base.Submodule = repo.submodule
+		cs.Repos = append(cs.Repos, base)
+		cs.Files = append(cs.Files, files...)
+		cs.Deleted = append(cs.Deleted, deleted...)
+	}
+
+	// 按配置排除文件
+	if opts.Config != nil && len(opts.Config.Exclude) > 0 {
+		cs.Files = filterChangedFiles(cs.Files, opts.Config)
+		cs.Deleted = filterChangedFiles(cs.Deleted, opts.Config)
+	}
+	// 跳过生成代码（已删除的文件无法读取标记，保持不变）
+	if opts.SkipGenerated {
+		kept := cs.Files[:0]
+		for _, f := range cs.Files {
+			if isGeneratedGoFile(f.Path, opts.Config) {
+				log.Printf("跳过生成代码: %s", f.Path)
+				cs.SkippedGenerated = append(cs.SkippedGenerated, f.Path)
+				continue
+			}
+			kept = append(kept, f)
+		}
+		cs.Files = kept
+	}
+
+	for _, f := range cs.Files {
+		if f.OldPath != "" {
+			log.Printf("收集到变更 Go 文件: %s [%s%d <- %s]", f.Path, f.Status, f.Similarity, f.OldPath)
+		} else {
+			log.Printf("收集到变更 Go 文件: %s [%s]", f.Path, f.Status)
+		}
+	}
+	for _, f := range cs.Deleted {
+		log.Printf("收集到删除的 Go 文件: %s", f.Path)
+	}
+
+	if len(cs.Files) == 0 && len(cs.Deleted) == 0 {
+		return nil, fmt.Errorf("未找到任何变更的 Go 文件（工作区与提交范围均为空）")
+	}
+	log.Printf("总共找到 %d 个变更的 Go 文件、%d 个删除的 Go 文件（%d 个仓库，工作区+提交范围）", len(cs.Files), len(cs.Deleted), len(cs.Repos))
+	return cs, nil
+}
+
+// filterChangedFiles 去掉被配置 exclude 排除的变更文件
+func filterChangedFiles(files []ChangedFile, cfg *EffectiveConfig) []ChangedFile {
+	kept := files[:0]
+	for _, f := range files {
+		if cfg.isExcluded(f.Path) {
+			log.Printf("按配置排除变更文件: %s", f.Path)
+			continue
+		}
+		kept = append(kept, f)
+	}
+	return kept
+}
+
+// nestedRepo 表示起点目录下的子模块或嵌套仓库
+type nestedRepo struct {
+	root      string
+	submodule bool
+}
+
+// discoverNestedRepos 查找起点目录下的子模块（git submodule status --recursive）和嵌套的 .git 仓库，
+// 遍历时与 discoverProjects 一样跳过 vendor、node_modules、testdata 与隐藏目录
+func discoverNestedRepos(repo GitRepo) []nestedRepo {
+	projectRoot := repo.Dir()
+	topLevel, _ := repo.TopLevel()
+	seen := map[string]bool{topLevel: true}
+	var repos []nestedRepo
+	add := func(dir string, submodule bool) {
+		dir = filepath.Clean(dir)
+		if seen[dir] || !isWithinDir(projectRoot, dir) {
+			return
+		}
+		seen[dir] = true
+		repos = append(repos, nestedRepo{root: dir, submodule: submodule})
+	}
+
+	// 已初始化的子模块，路径相对于仓库顶层
+	submodules, err := repo.Submodules()
+	logGitError("列出子模块", err)
+	for _, path := range submodules {
+		add(filepath.Join(topLevel, filepath.FromSlash(path)), true)
+	}
+
+	// 未注册为子模块的嵌套仓库：目录中存在 .git（目录或文件）
+	_ = filepath.WalkDir(projectRoot, func(path string, d os.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if path == projectRoot {
+			return nil
+		}
+		if isSkippedWalkDir(d.Name()) {
+			return filepath.SkipDir
+		}
+		if _, err := os.Lstat(filepath.Join(path, ".git")); err == nil {
+			add(path, false)
+		}
+		return nil
+	})
+
+	sort.Slice(repos, func(i, j int) bool { return repos[i].root < repos[j].root })
+	for _, r := range repos {
+		log.Printf("发现嵌套仓库: %s（子模块: %v）", r.root, r.submodule)
+	}
+	return repos
+}
+
+// isWithinDir 判断 path 是否位于 dir 之内（含 dir 本身）
+func isWithinDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// collectRepoChanges 检测单个仓库内（限于起点目录下）的变更 Go 文件
+func collectRepoChanges(repo GitRepo, opts ScopeOptions) ([]ChangedFile, []ChangedFile, RepoBase, error) {
+	repoDir := repo.Dir()
+	// 尝试检测基准提交点（用于提交范围）
+	base := detectBaseCommit(repo, opts)
+	baseCommit := base.BaseCommit
+	log.Printf("仓库 %s 使用检测策略: %s，基准提交: %s", repoDir, base.Strategy, baseCommit)
+	log.Printf("基准说明: %s", base.Explanation)
+
+	// git diff 输出的路径相对于仓库顶层目录
+	topLevel, err := repo.TopLevel()
+	if err != nil {
+		return nil, nil, base, fmt.Errorf("不是Git仓库: %s", repoDir)
+	}
+
+	// 基准提交与工作区比较，同时覆盖提交范围、已暂存和未暂存的变更
+	rev := baseCommit
+	if rev == "" {
+		rev = "HEAD"
+	}
+	var entries []ChangedFile
+	diffEntries, err := repo.DiffNameStatus(rev)
+	logGitError("获取差异文件", err)
+	for _, d := range diffEntries {
+		entries = append(entries, ChangedFile{Path: d.Path, OldPath: d.OldPath, Status: d.Status, Similarity: d.Similarity})
+	}
+
+	// 未跟踪的新文件（仅限起点目录下）
+	untracked, err := repo.UntrackedFiles()
+	logGitError("获取未跟踪文件", err)
+	for _, p := range untracked {
+		entries = append(entries, ChangedFile{Path: p, Status: "A"})
+	}
+
+	oldContent := func(relPath string) []string {
+		out, err := repo.ShowFile(rev, relPath)
+		if err != nil {
+			return nil
+		}
+		return splitLines(string(out))
+	}
+
+	var files, deleted, added []ChangedFile
+	seen := make(map[string]bool)
+	for _, e := range entries {
+		if !strings.HasSuffix(e.Path, ".go") && !strings.HasSuffix(e.OldPath, ".go") {
+			continue
+		}
+		relPath, relOld := e.Path, e.OldPath
+		e.Path = filepath.Join(topLevel, filepath.FromSlash(relPath))
+		if relOld != "" {
+			e.OldPath = filepath.Join(topLevel, filepath.FromSlash(relOld))
+		}
+		if seen[e.Path] {
+			continue
+		}
+		seen[e.Path] = true
+
+		if e.Status == "D" {
+			if _, err := os.Stat(e.Path); err == nil {
+				// 提交范围内删除但工作区又重新创建
+				e.Status = "M"
+			} else {
+				deleted = append(deleted, e)
+				continue
+			}
+		} else if _, err := os.Stat(e.Path); err != nil {
+			continue
+		}
+
+		if e.Status == "R" || e.Status == "C" {
+			if content, err := os.ReadFile(e.Path); err == nil {
+				e.lineMap = buildLineMap(oldContent(relOld), splitLines(string(content)))
+			}
+		}
+		if e.Status == "A" {
+			added = append(added, e)
+			continue
+		}
+		files = append(files, e)
+	}
+
+	// 未使用 git mv 的重命名表现为"删除 + 新增未跟踪文件"，按内容相似度配对
+	pairedDeleted := make(map[int]bool)
+	for _, a := range added {
+		content, err := os.ReadFile(a.Path)
+		if err != nil {
+			continue
+		}
+		newLines := splitLines(string(content))
+		best, bestSim := -1, 0
+		for i, d := range deleted {
+			if pairedDeleted[i] {
+				continue
+			}
+			relOld, err := filepath.Rel(topLevel, d.Path)
+			if err != nil {
+				continue
+			}
+			if sim := lineSimilarity(oldContent(filepath.ToSlash(relOld)), newLines); sim > bestSim {
+				best, bestSim = i, sim
+			}
+		}
+		if best >= 0 && bestSim >= renameSimilarityThreshold {
+			pairedDeleted[best] = true
+			relOld, _ := filepath.Rel(topLevel, deleted[best].Path)
+			a.Status = "R"
+			a.OldPath = deleted[best].Path
+			a.Similarity = bestSim
+			a.lineMap = buildLineMap(oldContent(filepath.ToSlash(relOld)), newLines)
+		}
+		files = append(files, a)
+	}
+	var remaining []ChangedFile
+	for i, d := range deleted {
+		if !pairedDeleted[i] {
+			remaining = append(remaining, d)
+		}
+	}
+	return files, remaining, base, nil
+}
@@ -0,0 +1,351 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// isolateConfig 避免测试读取用户级配置与临时目录之上的 .lint-mcp.yaml
+func isolateConfig(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv(userConfigEnv, filepath.Join(dir, "missing.yaml"))
+	return dir
+}
+
+func TestDetectBaseCommit(t *testing.T) {
+	tests := []struct {
+		name         string
+		repo         fakeGitRepo
+		opts         ScopeOptions
+		wantBase     string
+		wantStrategy string
+		wantTrunk    string
+	}{
+		{
+			name: "未推送的提交以远程分支为基准",
+			repo: fakeGitRepo{
+				Branch: "feat",
+				Refs:   map[string]string{"origin/feat": "f1"},
+				Counts: map[string]int{"origin/feat..HEAD": 2},
+			},
+			wantBase:     "origin/feat",
+			wantStrategy: "未推送的提交(2个)",
+		},
+		{
+			name: "已推送时使用最近主干的合并基点，远程分支优先",
+			repo: fakeGitRepo{
+				Branch:     "feat",
+				Refs:       map[string]string{"origin/feat": "f1"},
+				Counts:     map[string]int{"origin/feat..HEAD": 0, "m1..HEAD": 3, "d1..HEAD": 5},
+				BranchList: []GitBranch{{Name: "main"}, {Name: "main", Remote: "origin"}, {Name: "develop"}, {Name: "feat"}},
+				MergeBases: map[string]string{"HEAD...main": "m1", "HEAD...origin/main": "m1", "HEAD...develop": "d1"},
+			},
+			wantBase:     "m1",
+			wantStrategy: "分支分叉点(vs origin/main, 3个提交)",
+			wantTrunk:    "origin/main",
+		},
+		{
+			name: "选择领先提交数最少的主干",
+			repo: fakeGitRepo{
+				Branch:     "feat",
+				Counts:     map[string]int{"m1..HEAD": 7, "d1..HEAD": 2},
+				BranchList: []GitBranch{{Name: "main"}, {Name: "develop"}},
+				MergeBases: map[string]string{"HEAD...main": "m1", "HEAD...develop": "d1"},
+			},
+			wantBase:     "d1",
+			wantStrategy: "分支分叉点(vs develop, 2个提交)",
+			wantTrunk:    "develop",
+		},
+		{
+			name: "工具参数指定主干模式",
+			repo: fakeGitRepo{
+				Branch:     "fix",
+				Counts:     map[string]int{"m1..HEAD": 1, "r1..HEAD": 4},
+				BranchList: []GitBranch{{Name: "main"}, {Name: "release/1.2", Remote: "origin"}},
+				MergeBases: map[string]string{"HEAD...main": "m1", "HEAD...origin/release/1.2": "r1"},
+			},
+			opts:         ScopeOptions{TrunkBranches: []string{"release/*"}},
+			wantBase:     "r1",
+			wantStrategy: "分支分叉点(vs origin/release/1.2, 4个提交)",
+			wantTrunk:    "origin/release/1.2",
+		},
+		{
+			name: "没有主干时检查工作区变更",
+			repo: fakeGitRepo{
+				Branch:     "feat",
+				WorkStatus: GitStatus{Entries: []GitStatusEntry{{Kind: '1', XY: ".M", Path: "a.go"}}},
+			},
+			wantBase:     "",
+			wantStrategy: "工作区变更",
+		},
+		{
+			name: "主干均已包含 HEAD 且工作区干净时回退为最近几次提交",
+			repo: fakeGitRepo{
+				Branch:     "feat",
+				Refs:       map[string]string{"HEAD~2": "h2", "HEAD~3": "h3"},
+				Counts:     map[string]int{"m1..HEAD": 0},
+				BranchList: []GitBranch{{Name: "main"}},
+				MergeBases: map[string]string{"HEAD...main": "m1"},
+			},
+			wantBase:     "HEAD~2",
+			wantStrategy: "最近2次提交",
+		},
+		{
+			name: "位于已推送的主干上时不与其他主干比较",
+			repo: fakeGitRepo{
+				Branch:     "main",
+				Refs:       map[string]string{"origin/main": "m1"},
+				Counts:     map[string]int{"origin/main..HEAD": 0, "m1..HEAD": 0, "d1..HEAD": 120},
+				BranchList: []GitBranch{{Name: "main"}, {Name: "main", Remote: "origin"}, {Name: "develop"}},
+				MergeBases: map[string]string{"HEAD...main": "m1", "HEAD...origin/main": "m1", "HEAD...develop": "d1"},
+				WorkStatus: GitStatus{Entries: []GitStatusEntry{{Kind: '1', XY: ".M", Path: "a.go"}}},
+			},
+			wantBase:     "",
+			wantStrategy: "工作区变更",
+		},
+		{
+			name: "位于主干上且有未推送提交时以上游为基准",
+			repo: fakeGitRepo{
+				Branch:     "master",
+				Refs:       map[string]string{"origin/master": "m1"},
+				Counts:     map[string]int{"origin/master..HEAD": 1, "d1..HEAD": 120},
+				BranchList: []GitBranch{{Name: "master", Remote: "origin"}, {Name: "develop"}},
+				MergeBases: map[string]string{"HEAD...develop": "d1"},
+			},
+			wantBase:     "origin/master",
+			wantStrategy: "未推送的提交(1个)",
+		},
+		{
+			name:         "分离 HEAD 且历史很短",
+			repo:         fakeGitRepo{},
+			wantBase:     "HEAD~1",
+			wantStrategy: "最近一次提交",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := tt.repo
+			repo.DirPath = isolateConfig(t)
+			base := detectBaseCommit(&repo, tt.opts)
+			if base.BaseCommit != tt.wantBase || base.Strategy != tt.wantStrategy || base.Trunk != tt.wantTrunk {
+				t.Errorf("detectBaseCommit() = {BaseCommit: %q, Strategy: %q, Trunk: %q}, want {%q, %q, %q}\n说明: %s",
+					base.BaseCommit, base.Strategy, base.Trunk, tt.wantBase, tt.wantStrategy, tt.wantTrunk, base.Explanation)
+			}
+			if base.Explanation == "" {
+				t.Error("detectBaseCommit() 未给出基准说明")
+			}
+		})
+	}
+}
+
+// changedFileSummary 便于比较的 ChangedFile（路径相对仓库根目录）
+type changedFileSummary struct {
+	Path, OldPath, Status string
+	Similarity            int
+	ChangedLines          []int // 仅重命名/复制文件
+}
+
+func summarizeChangedFiles(top string, files []ChangedFile) []changedFileSummary {
+	var result []changedFileSummary
+	for _, f := range files {
+		s := changedFileSummary{Status: f.Status, Similarity: f.Similarity}
+		s.Path, _ = filepath.Rel(top, f.Path)
+		if f.OldPath != "" {
+			s.OldPath, _ = filepath.Rel(top, f.OldPath)
+		}
+		if f.lineMap != nil {
+			for line := range f.lineMap.changedLines {
+				s.ChangedLines = append(s.ChangedLines, line)
+			}
+			sort.Ints(s.ChangedLines)
+		}
+		result = append(result, s)
+	}
+	return result
+}
+
+func TestCollectRepoChanges(t *testing.T) {
+	const body = "package p\n\nfunc A() {}\n\nfunc B() {}\n\nfunc C() {}\n"
+	tests := []struct {
+		name        string
+		worktree    map[string]string // 工作区中存在的文件
+		repo        fakeGitRepo
+		wantFiles   []changedFileSummary
+		wantDeleted []changedFileSummary
+	}{
+		{
+			name:     "修改、未跟踪与非 Go 文件",
+			worktree: map[string]string{"a.go": body, "new.go": body, "README.md": "x"},
+			repo: fakeGitRepo{
+				Diffs:     map[string][]GitDiffEntry{"HEAD": {{Status: "M", Path: "a.go"}, {Status: "M", Path: "README.md"}}},
+				Untracked: []string{"new.go"},
+			},
+			wantFiles: []changedFileSummary{{Path: "a.go", Status: "M"}, {Path: "new.go", Status: "A"}},
+		},
+		{
+			name:     "删除的文件单独返回，工作区中重新创建的视为修改",
+			worktree: map[string]string{"back.go": body},
+			repo: fakeGitRepo{
+				Diffs: map[string][]GitDiffEntry{"HEAD": {{Status: "D", Path: "gone.go"}, {Status: "D", Path: "back.go"}}},
+			},
+			wantFiles:   []changedFileSummary{{Path: "back.go", Status: "M"}},
+			wantDeleted: []changedFileSummary{{Path: "gone.go", Status: "D"}},
+		},
+		{
+			name:     "git mv 重命名只标记修改过的行",
+			worktree: map[string]string{"pkg/b.go": "package p\n\nfunc A() {}\n\nfunc B2() {}\n\nfunc C() {}\n"},
+			repo: fakeGitRepo{
+				Diffs: map[string][]GitDiffEntry{"HEAD": {{Status: "R", Similarity: 80, OldPath: "a.go", Path: "pkg/b.go"}}},
+				Files: map[string][]byte{"HEAD:a.go": []byte(body)},
+			},
+			wantFiles: []changedFileSummary{{Path: "pkg/b.go", OldPath: "a.go", Status: "R", Similarity: 80, ChangedLines: []int{5}}},
+		},
+		{
+			name:     "删除加未跟踪文件按内容相似度配对为重命名",
+			worktree: map[string]string{"moved.go": body + "\nfunc D() {}\n"},
+			repo: fakeGitRepo{
+				Diffs:     map[string][]GitDiffEntry{"HEAD": {{Status: "D", Path: "orig.go"}}},
+				Untracked: []string{"moved.go"},
+				Files:     map[string][]byte{"HEAD:orig.go": []byte(body)},
+			},
+			wantFiles: []changedFileSummary{{Path: "moved.go", OldPath: "orig.go", Status: "R", Similarity: 87, ChangedLines: []int{8, 9}}},
+		},
+		{
+			name:     "内容差异过大时不配对",
+			worktree: map[string]string{"other.go": "package q\n\nvar X = 1\n"},
+			repo: fakeGitRepo{
+				Diffs:     map[string][]GitDiffEntry{"HEAD": {{Status: "D", Path: "orig.go"}}},
+				Untracked: []string{"other.go"},
+				Files:     map[string][]byte{"HEAD:orig.go": []byte(body)},
+			},
+			wantFiles:   []changedFileSummary{{Path: "other.go", Status: "A"}},
+			wantDeleted: []changedFileSummary{{Path: "orig.go", Status: "D"}},
+		},
+		{
+			name:     "以检测到的基准提交比较",
+			worktree: map[string]string{"a.go": body},
+			repo: fakeGitRepo{
+				Branch: "feat",
+				Refs:   map[string]string{"origin/feat": "f1"},
+				Counts: map[string]int{"origin/feat..HEAD": 1},
+				Diffs: map[string][]GitDiffEntry{
+					"HEAD":        {},
+					"origin/feat": {{Status: "A", Path: "a.go"}},
+				},
+			},
+			wantFiles: []changedFileSummary{{Path: "a.go", Status: "A"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			top := isolateConfig(t)
+			for name, content := range tt.worktree {
+				path := filepath.Join(top, filepath.FromSlash(name))
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			repo := tt.repo
+			repo.DirPath = top
+			if repo.WorkStatus.Entries == nil && len(repo.Diffs["HEAD"])+len(repo.Untracked) > 0 {
+				// 工作区存在变更，基准检测走"工作区变更"策略
+				repo.WorkStatus.Entries = []GitStatusEntry{{Kind: '1', XY: ".M"}}
+			}
+
+			files, deleted, _, err := collectRepoChanges(&repo, ScopeOptions{})
+			if err != nil {
+				t.Fatalf("collectRepoChanges() error = %v", err)
+			}
+			if got := summarizeChangedFiles(top, files); !reflect.DeepEqual(got, tt.wantFiles) {
+				t.Errorf("files = %+v, want %+v", got, tt.wantFiles)
+			}
+			if got := summarizeChangedFiles(top, deleted); !reflect.DeepEqual(got, tt.wantDeleted) {
+				t.Errorf("deleted = %+v, want %+v", got, tt.wantDeleted)
+			}
+		})
+	}
+}
+
+func TestDeletedPackages(t *testing.T) {
+	root := isolateConfig(t)
+	for _, name := range []string{"go.mod", "a/keep.go", "b/moved.go", "c/keep.go", "c/renamed.go", "empty/.keep"} {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("module m\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	abs := func(name string) string { return filepath.Join(root, filepath.FromSlash(name)) }
+
+	tests := []struct {
+		name string
+		cs   ChangeSet
+		want map[string][]string
+	}{
+		{
+			name: "删除文件的包仍有其他文件",
+			cs:   ChangeSet{Deleted: []ChangedFile{{Path: abs("a/gone.go"), Status: "D"}}},
+			want: map[string][]string{root: {"./a"}},
+		},
+		{
+			name: "删除后目录中已没有 Go 文件",
+			cs:   ChangeSet{Deleted: []ChangedFile{{Path: abs("empty/gone.go"), Status: "D"}}},
+			want: map[string][]string{},
+		},
+		{
+			name: "重命名移出目录时原目录的包需要重新检查",
+			cs:   ChangeSet{Files: []ChangedFile{{Path: abs("b/moved.go"), OldPath: abs("a/moved.go"), Status: "R"}}},
+			want: map[string][]string{root: {"./a"}},
+		},
+		{
+			name: "同目录内重命名不额外检查",
+			cs:   ChangeSet{Files: []ChangedFile{{Path: abs("c/renamed.go"), OldPath: abs("c/old.go"), Status: "R"}}},
+			want: map[string][]string{},
+		},
+		{
+			name: "复制不影响原目录",
+			cs:   ChangeSet{Files: []ChangedFile{{Path: abs("b/moved.go"), OldPath: abs("a/keep.go"), Status: "C"}}},
+			want: map[string][]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cs.DeletedPackages(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DeletedPackages() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiscoverNestedRepos(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"lib", "lib/inner", "vendor/dep", "node_modules/pkg", "testdata/fixture", ".cache/repo"} {
+		if err := os.MkdirAll(filepath.Join(root, filepath.FromSlash(name), ".git"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	repo := &fakeGitRepo{DirPath: root, SubmodulePath: []string{"third_party/sub"}}
+
+	var got []nestedRepo
+	for _, r := range discoverNestedRepos(repo) {
+		rel, _ := filepath.Rel(root, r.root)
+		got = append(got, nestedRepo{root: filepath.ToSlash(rel), submodule: r.submodule})
+	}
+	want := []nestedRepo{
+		{root: "lib"},
+		{root: "lib/inner"},
+		{root: "third_party/sub", submodule: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("discoverNestedRepos() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,411 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"gopkg.in/yaml.v3"
+)
+
+// projectConfigNames 项目配置文件名，按优先级排列
+var projectConfigNames = []string{".lint-mcp.yaml", ".lint-mcp.yml"}
+
+// userConfigEnv 指定用户级配置文件路径的环境变量，未设置时使用 <用户配置目录>/lint-mcp/config.yaml
+const userConfigEnv = "LINT_MCP_USER_CONFIG"
+
+// 后端名称，对应 backends 配置项
+const (
+	backendGolangciLint = "golangci-lint"
+	backendGofmt        = "gofmt"
+	backendGovulncheck  = "govulncheck"
+	backendGoTest       = "go-test"
+	backendGoBuild      = "go-build"
+)
+
+// knownBackends 全部可配置的后端
+var knownBackends = []string{backendGolangciLint, backendGofmt, backendGovulncheck, backendGoTest, backendGoBuild}
+
+// 输出格式
+const (
+	outputFormatJSON = "json"
+	outputFormatText = "text"
+)
+
+// ProjectConfig 表示 .lint-mcp.yaml 配置（项目级与用户级格式相同）
+type ProjectConfig struct {
+	// Defaults 所有工具共用的默认参数（调用时未传入的参数才会使用），如 checkOnlyChanges、dependentsDepth
+	Defaults map[string]interface{} `yaml:"defaults" json:"defaults,omitempty"`
+	// Tools 按工具名覆盖默认参数，如 tools.code_test.timeout
+	Tools map[string]map[string]interface{} `yaml:"tools" json:"tools,omitempty"`
+	// Exclude 排除的文件 glob（相对于项目根目录，支持 **；不含 / 的模式匹配文件名）
+	Exclude []string `yaml:"exclude" json:"exclude,omitempty"`
+	// GeneratedPatterns 额外视为生成代码的文件名 glob（带 "Code generated ... DO NOT EDIT." 标记的文件总是视为生成代码）
+	GeneratedPatterns []string `yaml:"generatedPatterns" json:"generatedPatterns,omitempty"`
+	// TrunkBranches 主干分支模式（如 "main"、"release/*"），用于确定变更检测的基准
+	TrunkBranches []string `yaml:"trunkBranches" json:"trunkBranches,omitempty"`
+	// OutputFormat 结果输出格式：json（默认）或 text
+	OutputFormat string `yaml:"outputFormat" json:"outputFormat,omitempty"`
+	// Backends 启用/禁用检查后端，未列出的后端默认启用
+	Backends map[string]bool `yaml:"backends" json:"backends,omitempty"`
+	// Limits golangci-lint 子进程的资源限制，按字段合并
+	Limits *ResourceLimits `yaml:"limits" json:"limits,omitempty"`
+	// Severity 问题严重程度映射与通过标准，按字段合并
+	Severity *SeverityConfig `yaml:"severity" json:"severity,omitempty"`
+}
+
+// ConfigSource 表示参与合并的一个配置文件
+type ConfigSource struct {
+	Kind  string `json:"kind"` // user 或 project
+	Path  string `json:"path"`
+	Error string `json:"error,omitempty"`
+}
+
+// EffectiveConfig 内置默认值、用户级配置与项目级配置合并后的生效配置
+type EffectiveConfig struct {
+	ProjectConfig
+	// ProjectRoot 项目配置所在目录，exclude 模式相对于该目录；没有项目配置时为起点目录
+	ProjectRoot string `json:"projectRoot"`
+	// Sources 按合并顺序列出的配置文件（后者覆盖前者）
+	Sources []ConfigSource `json:"sources"`
+	// FieldSources 各配置项的最终来源：builtin 或配置文件路径
+	FieldSources map[string]string `json:"fieldSources"`
+}
+
+// findProjectConfig 从 startDir 开始逐级向上查找项目配置文件，未找到时返回空字符串
+func findProjectConfig(startDir string) string {
+	dir, err := filepath.Abs(startDir)
+	if err != nil {
+		return ""
+	}
+	for {
+		for _, name := range projectConfigNames {
+			path := filepath.Join(dir, name)
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				return path
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// findUserConfig 返回用户级配置文件路径，文件不存在时返回空字符串
+func findUserConfig() string {
+	path := os.Getenv(userConfigEnv)
+	if path == "" {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return ""
+		}
+		path = filepath.Join(dir, "lint-mcp", "config.yaml")
+	}
+	if info, err := os.Stat(path); err == nil && !info.IsDir() {
+		return path
+	}
+	return ""
+}
+
+// loadConfigFile 读取并解析单个配置文件，未知的配置项视为错误以便发现拼写问题
+func loadConfigFile(path string) (*ProjectConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &ProjectConfig{}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("解析配置文件 %s 失败: %v", path, err)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("配置文件 %s 无效: %v", path, err)
+	}
+	return cfg, nil
+}
+
+// validate 检查配置取值
+func (c *ProjectConfig) validate() error {
+	switch c.OutputFormat {
+	case "", outputFormatJSON, outputFormatText:
+	default:
+		return fmt.Errorf("outputFormat 只支持 %s 或 %s，实际为 %q", outputFormatJSON, outputFormatText, c.OutputFormat)
+	}
+	for name := range c.Backends {
+		if !containsString(knownBackends, name) {
+			return fmt.Errorf("未知的后端 %q（可选: %s）", name, strings.Join(knownBackends, ", "))
+		}
+	}
+	if err := c.Limits.validate(); err != nil {
+		return err
+	}
+	if err := c.Severity.validate(); err != nil {
+		return err
+	}
+	for _, pattern := range append(append([]string(nil), c.Exclude...), c.GeneratedPatterns...) {
+		if _, err := path.Match(strings.ReplaceAll(pattern, "**", "*"), ""); err != nil {
+			return fmt.Errorf("无效的 glob 模式 %q: %v", pattern, err)
+		}
+	}
+	return nil
+}
+
+// loadEffectiveConfig 加载 startDir 所属项目的生效配置：内置默认值 < 用户级配置 < 项目级配置
+// 配置文件无法解析时记录日志并忽略该文件，不影响检查
+func loadEffectiveConfig(startDir string) *EffectiveConfig {
+	eff := loadBaseConfig(startDir)
+	if projectPath := findProjectConfig(startDir); projectPath != "" {
+		if eff.mergeFile("project", projectPath) {
+			eff.ProjectRoot = filepath.Dir(projectPath)
+		}
+	}
+	return eff
+}
+
+// loadBaseConfig 加载不含项目配置的生效配置：内置默认值 < 用户级配置
+func loadBaseConfig(startDir string) *EffectiveConfig {
+	eff := &EffectiveConfig{
+		ProjectConfig: ProjectConfig{
+			Defaults:     map[string]interface{}{},
+			Tools:        map[string]map[string]interface{}{},
+			OutputFormat: outputFormatJSON,
+			Backends:     map[string]bool{},
+			Limits:       &ResourceLimits{},
+			Severity:     &SeverityConfig{Default: severityWarning, FailOn: severityWarning},
+		},
+		ProjectRoot:  startDir,
+		Sources:      []ConfigSource{},
+		FieldSources: map[string]string{"outputFormat": "builtin", "severity.default": "builtin", "severity.failOn": "builtin"},
+	}
+	for _, name := range knownBackends {
+		eff.Backends[name] = true
+		eff.FieldSources["backends."+name] = "builtin"
+	}
+
+	if userPath := findUserConfig(); userPath != "" {
+		eff.mergeFile("user", userPath)
+	}
+	return eff
+}
+
+// mergeFile 读取配置文件并合并到生效配置，返回是否合并成功
+func (e *EffectiveConfig) mergeFile(kind, path string) bool {
+	cfg, err := loadConfigFile(path)
+	if err != nil {
+		log.Printf("⚠️ %v，忽略该配置", err)
+		e.Sources = append(e.Sources, ConfigSource{Kind: kind, Path: path, Error: err.Error()})
+		return false
+	}
+	log.Printf("加载%s配置: %s", map[string]string{"user": "用户级", "project": "项目"}[kind], path)
+	e.Sources = append(e.Sources, ConfigSource{Kind: kind, Path: path})
+	e.merge(cfg, path)
+	return true
+}
+
+// merge 将 cfg 覆盖到生效配置：列表与标量整体替换，defaults/tools/backends 按键合并
+func (e *EffectiveConfig) merge(cfg *ProjectConfig, source string) {
+	for k, v := range cfg.Defaults {
+		e.Defaults[k] = v
+		e.FieldSources["defaults."+k] = source
+	}
+	for tool, args := range cfg.Tools {
+		if e.Tools[tool] == nil {
+			e.Tools[tool] = map[string]interface{}{}
+		}
+		for k, v := range args {
+			e.Tools[tool][k] = v
+			e.FieldSources["tools."+tool+"."+k] = source
+		}
+	}
+	for name, enabled := range cfg.Backends {
+		e.Backends[name] = enabled
+		e.FieldSources["backends."+name] = source
+	}
+	if cfg.Exclude != nil {
+		e.Exclude = cfg.Exclude
+		e.FieldSources["exclude"] = source
+	}
+	if cfg.GeneratedPatterns != nil {
+		e.GeneratedPatterns = cfg.GeneratedPatterns
+		e.FieldSources["generatedPatterns"] = source
+	}
+	if cfg.TrunkBranches != nil {
+		e.TrunkBranches = cfg.TrunkBranches
+		e.FieldSources["trunkBranches"] = source
+	}
+	if cfg.OutputFormat != "" {
+		e.OutputFormat = cfg.OutputFormat
+		e.FieldSources["outputFormat"] = source
+	}
+	if cfg.Limits != nil {
+		for _, field := range e.Limits.merge(cfg.Limits) {
+			e.FieldSources["limits."+field] = source
+		}
+	}
+	if cfg.Severity != nil {
+		for _, field := range e.Severity.merge(cfg.Severity) {
+			e.FieldSources["severity."+field] = source
+		}
+	}
+}
+
+// toolDefaults 返回指定工具的默认参数（tools.<name> 覆盖 defaults）
+func (e *EffectiveConfig) toolDefaults(tool string) map[string]interface{} {
+	args := make(map[string]interface{}, len(e.Defaults))
+	for k, v := range e.Defaults {
+		args[k] = v
+	}
+	for k, v := range e.Tools[tool] {
+		args[k] = v
+	}
+	return args
+}
+
+// backendEnabled 判断后端是否启用
+func (e *EffectiveConfig) backendEnabled(name string) bool {
+	enabled, ok := e.Backends[name]
+	return !ok || enabled
+}
+
+// isExcluded 判断文件是否被 exclude 排除
+func (e *EffectiveConfig) isExcluded(file string) bool {
+	return matchAnyGlob(e.Exclude, e.ProjectRoot, file)
+}
+
+// isGeneratedName 判断文件名是否匹配生成代码模式
+func (e *EffectiveConfig) isGeneratedName(file string) bool {
+	return matchAnyGlob(e.GeneratedPatterns, e.ProjectRoot, file)
+}
+
+// applyToolDefaults 加载起点目录的生效配置，并将工具默认参数填入调用方未传入的参数。
+// 起点目录与处理函数一致（resolveBaseDir），先通过工作区校验再读取其中的项目配置；
+// 无法确定或不在工作区内时只使用内置默认值与用户级配置，请求随后由处理函数拒绝
+func applyToolDefaults(tool string, args map[string]interface{}) *EffectiveConfig {
+	var cfg *EffectiveConfig
+	if startDir, err := resolveBaseDir(startPathArgs(args)); err != nil {
+		log.Printf("起点目录无效，不读取项目配置: %v", err)
+		cfg = loadBaseConfig("")
+	} else {
+		cfg = loadEffectiveConfig(startDir)
+	}
+	for k, v := range cfg.toolDefaults(tool) {
+		if _, exists := args[k]; !exists {
+			args[k] = v
+			log.Printf("使用配置默认参数 %s=%v", k, v)
+		}
+	}
+	return cfg
+}
+
+// matchAnyGlob 判断文件是否匹配任一模式；模式相对于 root，不含 / 的模式只匹配文件名
+func matchAnyGlob(patterns []string, root, file string) bool {
+	if len(patterns) == 0 {
+		return false
+	}
+	rel := filepath.Base(file)
+	if root != "" {
+		if r, err := filepath.Rel(root, file); err == nil && !strings.HasPrefix(r, "..") {
+			rel = r
+		}
+	}
+	rel = filepath.ToSlash(rel)
+	base := path.Base(rel)
+	for _, pattern := range patterns {
+		if !strings.Contains(pattern, "/") {
+			if ok, _ := path.Match(pattern, base); ok {
+				return true
+			}
+			continue
+		}
+		if matchGlob(strings.TrimPrefix(pattern, "/"), rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob 按路径段匹配 glob，"**" 匹配任意层目录（含零层）
+func matchGlob(pattern, name string) bool {
+	return matchGlobParts(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchGlobParts(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchGlobParts(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// containsString 判断切片中是否包含指定字符串
+func containsString(items []string, s string) bool {
+	for _, item := range items {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// LintConfigRequest 定义配置查询请求结构
+type LintConfigRequest struct {
+	Files       []string `json:"files" description:"参考文件列表（可选，用于确定项目位置）"`
+	ProjectPath string   `json:"projectPath" description:"项目根目录（可选，优先作为配置查找起点）"`
+}
+
+// handleLintConfigRequest 返回指定项目合并后的生效配置及各配置项来源
+func handleLintConfigRequest(ctx context.Context, req mcp.CallToolRequest) (result *mcp.CallToolResult, err error) {
+	// 捕获任何 panic 并转换为错误返回
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("发生 panic: %v", r)
+			result = buildErrorResult(fmt.Sprintf("内部错误: %v", r))
+			err = nil
+		}
+	}()
+
+	log.Printf("收到配置查询请求: name=%s args=%v", req.Params.Name, req.Params.Arguments)
+
+	var configReq LintConfigRequest
+	if req.Params.Arguments == nil {
+		req.Params.Arguments = map[string]interface{}{}
+	}
+	argsBytes, _ := json.Marshal(req.Params.Arguments)
+	if err := json.Unmarshal(argsBytes, &configReq); err != nil {
+		return buildErrorResult(fmt.Sprintf("无效的请求参数: %v", err)), nil
+	}
+
+	baseDir, err := resolveBaseDir(configReq.ProjectPath, configReq.Files)
+	if err != nil {
+		return buildPathErrorResult(err), nil
+	}
+
+	cfg := loadEffectiveConfig(baseDir)
+	resultJSON, _ := json.Marshal(cfg)
+	return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Type: "text", Text: string(resultJSON)}}}, nil
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// projectCoordinator 按项目根目录协调检查请求：参数完全相同的进行中请求合并为一次执行，
+// 同一项目的其他请求排队依次执行，避免多个 golangci-lint / go 进程争用同一项目的缓存锁
+type projectCoordinator struct {
+	mu       sync.Mutex
+	projects map[string]*projectQueue
+}
+
+// projectQueue 单个项目的执行队列
+type projectQueue struct {
+	running  *coordinatedRun
+	waiting  []*coordinatedRun
+	inflight map[string]*coordinatedRun // 请求键 -> 执行中或排队中的请求
+}
+
+// coordinatedRun 一次实际执行，可被多个调用方共享
+type coordinatedRun struct {
+	key       string
+	start     chan struct{} // 轮到该请求执行时关闭
+	done      chan struct{} // 执行完成后关闭
+	result    *mcp.CallToolResult
+	err       error
+	reporters []*progressReporter // 所有等待该结果的调用方
+	waiters   int                 // 尚未取消的调用方数量，为 0 时不再执行或取消执行
+	started   bool                // 已有调用方开始执行
+	cancel    context.CancelFunc  // 取消执行，所有调用方都已取消时调用
+}
+
+// coordinator 全局协调器
+var coordinator = &projectCoordinator{projects: make(map[string]*projectQueue)}
+
+// coordinated 包装工具处理函数，使其经过项目协调器执行
+func coordinated(tool string, handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return coordinator.do(ctx, tool, req, handler)
+	}
+}
+
+// do 在项目队列中执行请求；无法确定项目根目录时（如缺少参数）直接执行，由处理函数返回错误
+func (c *projectCoordinator) do(ctx context.Context, tool string, req mcp.CallToolRequest, handler server.ToolHandlerFunc) (*mcp.CallToolResult, error) {
+	args := req.Params.Arguments
+	if args == nil || !hasStartPath(args) {
+		return handler(ctx, req)
+	}
+	root, err := resolveBaseDir(startPathArgs(args))
+	if err != nil {
+		return handler(ctx, req)
+	}
+	key, err := requestKey(ctx, tool, args)
+	if err != nil {
+		return handler(ctx, req)
+	}
+
+	reporter := newProgressReporter(ctx, req)
+	run, merged := c.enqueue(root, key, reporter)
+	if merged {
+		log.Printf("项目 %s 已有相同的 %s 请求在执行，合并等待其结果", root, tool)
+		reporter.report("与进行中的相同请求合并，等待其结果")
+	}
+
+	select {
+	case <-run.start:
+	case <-ctx.Done():
+		c.abandon(root, run, reporter)
+		return cancelledResult(ctx, tool), nil
+	}
+	// 轮到该请求时由第一个仍在等待的调用方启动执行。执行使用独立的 ctx，
+	// 只有所有调用方都取消后才取消，单个调用方取消不影响其余合并的调用方
+	if runCtx, ok := c.claim(ctx, run); ok {
+		reporter.report(fmt.Sprintf("开始执行 %s", tool))
+		go c.execute(runCtx, root, run, req, handler)
+	}
+	select {
+	case <-run.done:
+		return run.result, run.err
+	case <-ctx.Done():
+		c.abandon(root, run, reporter)
+		return cancelledResult(ctx, tool), nil
+	}
+}
+
+// execute 执行处理函数，完成后唤醒所有等待的调用方
+func (c *projectCoordinator) execute(ctx context.Context, root string, run *coordinatedRun, req mcp.CallToolRequest, handler server.ToolHandlerFunc) {
+	defer c.finish(root, run)
+	run.result, run.err = handler(ctx, req)
+}
+
+// cancelledResult 调用方在排队或等待合并结果期间取消请求时的返回
+func cancelledResult(ctx context.Context, tool string) *mcp.CallToolResult {
+	log.Printf("%s 请求在等待期间被取消: %v", tool, ctx.Err())
+	return buildErrorResult(fmt.Sprintf("请求已取消: %v", ctx.Err()))
+}
+
+// requestKey 请求的合并键：工具名 + 参数（json.Marshal 对 map 按键排序，结果稳定）
+func requestKey(ctx context.Context, tool string, args map[string]interface{}) (string, error) {
+	data, err := json.Marshal(args)
+	if err != nil {
+		return "", err
+	}
+	key := tool + " " + string(data)
+	// 内部汇总用的子请求强制 JSON 输出，不能与普通请求共享结果
+	if ctx.Value(jsonResultKey{}) != nil {
+		key += " json"
+	}
+	return key, nil
+}
+
+// enqueue 加入项目队列：存在相同请求时附加到该请求上（merged=true），否则新建执行并排队
+func (c *projectCoordinator) enqueue(root, key string, reporter *progressReporter) (*coordinatedRun, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	q := c.projects[root]
+	if q == nil {
+		q = &projectQueue{inflight: make(map[string]*coordinatedRun)}
+		c.projects[root] = q
+	}
+	if run, ok := q.inflight[key]; ok {
+		run.reporters = append(run.reporters, reporter)
+		run.waiters++
+		return run, true
+	}
+
+	run := &coordinatedRun{key: key, start: make(chan struct{}), done: make(chan struct{}), reporters: []*progressReporter{reporter}, waiters: 1}
+	q.inflight[key] = run
+	if q.running == nil {
+		q.running = run
+		close(run.start)
+	} else {
+		q.waiting = append(q.waiting, run)
+		log.Printf("项目 %s 有请求正在执行，排队位置 %d", root, len(q.waiting))
+		reporter.report(fmt.Sprintf("同一项目有其他请求正在执行，排队位置 %d", len(q.waiting)))
+	}
+	return run, false
+}
+
+// claim 轮到执行时认领该请求，只有第一个认领的调用方启动执行；
+// 返回的 ctx 保留调用方 ctx 中的值但不随其取消
+func (c *projectCoordinator) claim(ctx context.Context, run *coordinatedRun) (context.Context, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if run.started {
+		return nil, false
+	}
+	run.started = true
+	runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	run.cancel = cancel
+	return runCtx, true
+}
+
+// abandon 调用方取消等待：所有调用方都已取消时，尚未开始执行的请求移出队列不再执行，
+// 已在执行的请求取消执行（之后的相同请求不再合并到该请求上）
+func (c *projectCoordinator) abandon(root string, run *coordinatedRun, reporter *progressReporter) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	run.waiters--
+	for i, r := range run.reporters {
+		if r == reporter {
+			run.reporters = append(run.reporters[:i], run.reporters[i+1:]...)
+			break
+		}
+	}
+	if run.waiters > 0 {
+		return
+	}
+	q := c.projects[root]
+	delete(q.inflight, run.key)
+	if run.started {
+		log.Printf("项目 %s 执行中请求的调用方已全部取消，取消执行", root)
+		run.cancel()
+		return
+	}
+	if q.running == run {
+		// 已轮到该请求但无人执行：直接启动下一个
+		close(run.done)
+		c.advance(root, q)
+		return
+	}
+	for i, waiting := range q.waiting {
+		if waiting == run {
+			q.waiting = append(q.waiting[:i], q.waiting[i+1:]...)
+			break
+		}
+	}
+	log.Printf("项目 %s 的排队请求已全部取消，移出队列", root)
+	c.reportPositions(q)
+}
+
+// finish 结束当前执行：唤醒所有合并的调用方，启动队列中的下一个请求并通知其余请求新的排队位置
+func (c *projectCoordinator) finish(root string, run *coordinatedRun) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	q := c.projects[root]
+	if q.inflight[run.key] == run {
+		delete(q.inflight, run.key)
+	}
+	run.cancel()
+	close(run.done)
+	c.advance(root, q)
+}
+
+// advance 启动队列中的下一个请求并通知其余请求新的排队位置；调用方需持有 c.mu
+func (c *projectCoordinator) advance(root string, q *projectQueue) {
+	q.running = nil
+	if len(q.waiting) == 0 {
+		delete(c.projects, root)
+		return
+	}
+	q.running, q.waiting = q.waiting[0], q.waiting[1:]
+	close(q.running.start)
+	c.reportPositions(q)
+}
+
+// reportPositions 通知排队中的请求当前排队位置；调用方需持有 c.mu
+func (c *projectCoordinator) reportPositions(q *projectQueue) {
+	for i, waiting := range q.waiting {
+		for _, r := range waiting.reporters {
+			r.report(fmt.Sprintf("排队位置 %d", i+1))
+		}
+	}
+}
+
+// progressReporter 通过 notifications/progress 向调用方报告进度（请求未携带 progressToken 时不发送）
+type progressReporter struct {
+	ctx   context.Context
+	token mcp.ProgressToken
+	mu    sync.Mutex
+	step  int
+}
+
+func newProgressReporter(ctx context.Context, req mcp.CallToolRequest) *progressReporter {
+	r := &progressReporter{ctx: ctx}
+	if req.Params.Meta != nil {
+		r.token = req.Params.Meta.ProgressToken
+	}
+	return r
+}
+
+// report 发送一条进度通知；progress 按调用方单调递增
+func (r *progressReporter) report(message string) {
+	if r == nil || r.token == nil {
+		return
+	}
+	srv := server.ServerFromContext(r.ctx)
+	if srv == nil {
+		return
+	}
+	r.mu.Lock()
+	r.step++
+	step := r.step
+	r.mu.Unlock()
+	err := srv.SendNotificationToClient(r.ctx, "notifications/progress", map[string]interface{}{
+		"progressToken": r.token,
+		"progress":      step,
+		"message":       message,
+	})
+	if err != nil {
+		log.Printf("发送进度通知失败: %v", err)
+	}
+}
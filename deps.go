@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// goListPackage go list -json 输出中与依赖分析相关的字段
+type goListPackage struct {
+	Dir          string   `json:"Dir"`
+	ImportPath   string   `json:"ImportPath"`
+	Standard     bool     `json:"Standard"`
+	Imports      []string `json:"Imports"`
+	TestImports  []string `json:"TestImports"`
+	XTestImports []string `json:"XTestImports"`
+	Module       *struct {
+		Path string `json:"Path"`
+		Main bool   `json:"Main"`
+	} `json:"Module"`
+}
+
+// DependentPackage 表示因依赖变更包而被加入检查范围的包
+type DependentPackage struct {
+	Package     string   `json:"Package"`
+	ImportPath  string   `json:"ImportPath"`
+	ProjectRoot string   `json:"ProjectRoot"`
+	Depth       int      `json:"Depth"`
+	Reason      string   `json:"Reason"`
+	Chain       []string `json:"Chain"`
+}
+
+// listModulePackages 列出模块内的全部包（含依赖，由调用方按需过滤），请求取消时终止 go list
+func listModulePackages(ctx context.Context, projectRoot string, vendorMode bool) ([]goListPackage, error) {
+	args := []string{"list", "-e", "-deps", "-json"}
+	if vendorMode {
+		args = append(args, "-mod=vendor")
+	}
+	args = append(args, "./...")
+	log.Printf("执行命令: go %v", args)
+
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Dir = projectRoot
+	cmd.Env = os.Environ()
+	output, err := cmd.Output()
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("请求已取消: %w", ctx.Err())
+	}
+	if err != nil && len(output) == 0 {
+		return nil, fmt.Errorf("go list 执行失败: %v", err)
+	}
+
+	var pkgs []goListPackage
+	dec := json.NewDecoder(bytes.NewReader(output))
+	for {
+		var pkg goListPackage
+		if err := dec.Decode(&pkg); err != nil {
+			if err != io.EOF {
+				return nil, fmt.Errorf("go list 输出解析失败: %v", err)
+			}
+			break
+		}
+		pkgs = append(pkgs, pkg)
+	}
+	return pkgs, nil
+}
+
+// isModulePackage 判断包是否属于当前主模块（排除标准库和外部依赖）
+func isModulePackage(pkg goListPackage) bool {
+	if pkg.Standard || pkg.Dir == "" {
+		return false
+	}
+	if pkg.Module != nil {
+		return pkg.Module.Main
+	}
+	return false
+}
+
+// expandDependents 基于反向导入图，查找导入（含测试导入）了指定包的模块内其他包
+// packages 为相对于项目根目录的包路径（如 "./pkg/foo"）；maxDepth 为1时只包含直接导入方，
+// 大于1时按层级传递展开，小于等于0表示不限层级
+func expandDependents(ctx context.Context, projectRoot string, packages []string, maxDepth int, vendorMode bool) ([]DependentPackage, error) {
+	pkgs, err := listModulePackages(ctx, projectRoot, vendorMode)
+	if err != nil {
+		return nil, err
+	}
+
+	// 建立 包路径 <-> 导入路径 映射，以及反向导入图（被导入方 -> 导入方）
+	relToImport := make(map[string]string)
+	importToRel := make(map[string]string)
+	reverse := make(map[string][]string)
+	for _, pkg := range pkgs {
+		if !isModulePackage(pkg) {
+			continue
+		}
+		rel, err := toRelPackagePath(projectRoot, pkg.Dir)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		relToImport[rel] = pkg.ImportPath
+		importToRel[pkg.ImportPath] = rel
+
+		seen := make(map[string]bool)
+		for _, list := range [][]string{pkg.Imports, pkg.TestImports, pkg.XTestImports} {
+			for _, imp := range list {
+				if imp == pkg.ImportPath || seen[imp] {
+					continue
+				}
+				seen[imp] = true
+				reverse[imp] = append(reverse[imp], pkg.ImportPath)
+			}
+		}
+	}
+
+	// 按层级广度优先展开，记录每个包被纳入的原因链
+	chains := make(map[string][]string)
+	var frontier []string
+	for _, rel := range packages {
+		importPath, ok := relToImport[rel]
+		if !ok {
+			continue
+		}
+		if _, exists := chains[importPath]; !exists {
+			chains[importPath] = []string{rel}
+			frontier = append(frontier, importPath)
+		}
+	}
+
+	var result []DependentPackage
+	for depth := 1; len(frontier) > 0 && (maxDepth <= 0 || depth <= maxDepth); depth++ {
+		sort.Strings(frontier)
+		var next []string
+		for _, target := range frontier {
+			importers := append([]string(nil), reverse[target]...)
+			sort.Strings(importers)
+			for _, importer := range importers {
+				if _, exists := chains[importer]; exists {
+					continue
+				}
+				rel := importToRel[importer]
+				if rel == "" {
+					continue
+				}
+				chain := append([]string{rel}, chains[target]...)
+				chains[importer] = chain
+
+				changed := chain[len(chain)-1]
+				reason := fmt.Sprintf("直接导入了变更包 %s", changed)
+				if depth > 1 {
+					reason = fmt.Sprintf("通过 %s 间接依赖变更包 %s（第%d层）", strings.Join(chain[1:], " -> "), changed, depth)
+				}
+				result = append(result, DependentPackage{
+					Package:     rel,
+					ImportPath:  importer,
+					ProjectRoot: projectRoot,
+					Depth:       depth,
+					Reason:      reason,
+					Chain:       chain,
+				})
+				next = append(next, importer)
+			}
+		}
+		frontier = next
+	}
+
+	log.Printf("项目 %s 中依赖变更包的包: %d 个", projectRoot, len(result))
+	return result, nil
+}
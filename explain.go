@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"gopkg.in/yaml.v3"
+)
+
+// linterDocsYAML 内置的 linter 文档，编译进二进制以便离线使用
+//
+//go:embed linterdocs/linters.yaml
+var linterDocsYAML []byte
+
+// LinterDoc 单个 linter 或规则的说明
+type LinterDoc struct {
+	Summary   string                `yaml:"summary"`
+	Rationale string                `yaml:"rationale"`
+	Bad       string                `yaml:"bad"`
+	Good      string                `yaml:"good"`
+	Config    string                `yaml:"config"`
+	Rules     map[string]*LinterDoc `yaml:"rules"`
+}
+
+// ruleConfigTemplates 按规则关闭检查的 golangci-lint 配置模板，%s 为规则名
+var ruleConfigTemplates = map[string]string{
+	"gocritic":    "linters-settings:\n  gocritic:\n    disabled-checks:\n      - %s\n",
+	"revive":      "linters-settings:\n  revive:\n    rules:\n      - name: %s\n        disabled: true\n",
+	"gosec":       "linters-settings:\n  gosec:\n    excludes:\n      - %s\n",
+	"staticcheck": "linters-settings:\n  staticcheck:\n    checks: [\"all\", \"-%s\"]\n",
+	"gosimple":    "linters-settings:\n  gosimple:\n    checks: [\"all\", \"-%s\"]\n",
+	"stylecheck":  "linters-settings:\n  stylecheck:\n    checks: [\"all\", \"-%s\"]\n",
+	"govet":       "linters-settings:\n  govet:\n    disable:\n      - %s\n",
+}
+
+// ruleFromMessageRegex 从问题文本中提取规则名，如 "ifElseChain: rewrite ..."、"SA4006: ..."、"G104: ..."
+var ruleFromMessageRegex = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9_-]*):\s`)
+
+var (
+	linterDocsOnce sync.Once
+	linterDocs     map[string]*LinterDoc
+	linterDocsErr  error
+)
+
+// loadLinterDocs 解析内置文档（只解析一次）
+func loadLinterDocs() (map[string]*LinterDoc, error) {
+	linterDocsOnce.Do(func() {
+		linterDocs = make(map[string]*LinterDoc)
+		linterDocsErr = yaml.Unmarshal(linterDocsYAML, &linterDocs)
+	})
+	return linterDocs, linterDocsErr
+}
+
+// LintExplainRequest lint_explain 工具参数
+type LintExplainRequest struct {
+	Linter  string `json:"linter" description:"linter 名称，即问题的 FromLinter，如 gocritic、revive"`
+	Rule    string `json:"rule" description:"规则名（可选），如 ifElseChain、exported、SA4006"`
+	Message string `json:"message" description:"问题文本（可选），未指定 rule 时从中提取规则名"`
+}
+
+// LintExplainResult lint_explain 工具结果
+type LintExplainResult struct {
+	Linter      string   `json:"Linter"`
+	Rule        string   `json:"Rule,omitempty"`
+	Found       bool     `json:"Found"`     // 内置文档中是否有该 linter
+	RuleFound   bool     `json:"RuleFound"` // 是否命中具体规则的说明
+	Summary     string   `json:"Summary"`
+	Rationale   string   `json:"Rationale,omitempty"`
+	BadExample  string   `json:"BadExample,omitempty"`
+	GoodExample string   `json:"GoodExample,omitempty"`
+	Nolint      string   `json:"Nolint"`
+	Config      string   `json:"Config,omitempty"`
+	KnownRules  []string `json:"KnownRules,omitempty"` // 未命中规则时列出内置文档覆盖的规则
+	DocsURL     string   `json:"DocsURL,omitempty"`
+}
+
+// explainLinter 查找 linter（及规则）的说明；规则说明中缺省的字段沿用 linter 级说明
+func explainLinter(linter, rule, message string) (*LintExplainResult, error) {
+	docs, err := loadLinterDocs()
+	if err != nil {
+		return nil, fmt.Errorf("解析内置 linter 文档失败: %v", err)
+	}
+
+	linter = strings.TrimSpace(linter)
+	rule = strings.TrimSpace(rule)
+	if rule == "" {
+		if m := ruleFromMessageRegex.FindStringSubmatch(strings.TrimSpace(message)); m != nil {
+			rule = m[1]
+		}
+	}
+
+	result := &LintExplainResult{Linter: linter, Rule: rule}
+	if !strings.Contains(linter, " ") && linter != "govulncheck" {
+		result.Nolint = fmt.Sprintf("//nolint:%s // 说明忽略原因", linter)
+		result.DocsURL = "https://golangci-lint.run/usage/linters/#" + linter
+	} else {
+		result.Nolint = "不适用：该问题不是 golangci-lint 报告的，无法通过 //nolint 忽略"
+	}
+
+	doc, ok := docs[linter]
+	if !ok {
+		result.Summary = fmt.Sprintf("内置文档未收录 %s，请参考 golangci-lint 文档", linter)
+		return result, nil
+	}
+	result.Found = true
+	result.Summary = doc.Summary
+	result.Rationale = doc.Rationale
+	result.BadExample = doc.Bad
+	result.GoodExample = doc.Good
+	result.Config = doc.Config
+
+	if rule != "" {
+		if ruleDoc, name := lookupRule(doc, rule); ruleDoc != nil {
+			result.Rule = name
+			result.RuleFound = true
+			result.Summary = ruleDoc.Summary
+			if ruleDoc.Rationale != "" {
+				result.Rationale = ruleDoc.Rationale
+			}
+			if ruleDoc.Bad != "" || ruleDoc.Good != "" {
+				result.BadExample = ruleDoc.Bad
+				result.GoodExample = ruleDoc.Good
+			}
+		}
+		// 按规则关闭比关闭整个 linter 更精确；//nolint 只能按 linter 粒度忽略
+		if tmpl, ok := ruleConfigTemplates[linter]; ok {
+			result.Config = fmt.Sprintf(tmpl, result.Rule)
+		}
+	}
+	if !result.RuleFound {
+		for name := range doc.Rules {
+			result.KnownRules = append(result.KnownRules, name)
+		}
+		sort.Strings(result.KnownRules)
+	}
+	return result, nil
+}
+
+// lookupRule 按规则名查找（不区分大小写），返回规则说明及其规范名称
+func lookupRule(doc *LinterDoc, rule string) (*LinterDoc, string) {
+	if ruleDoc, ok := doc.Rules[rule]; ok {
+		return ruleDoc, rule
+	}
+	for name, ruleDoc := range doc.Rules {
+		if strings.EqualFold(name, rule) {
+			return ruleDoc, name
+		}
+	}
+	return nil, ""
+}
+
+// handleLintExplainRequest 处理 linter 说明查询请求
+func handleLintExplainRequest(ctx context.Context, req mcp.CallToolRequest) (result *mcp.CallToolResult, err error) {
+	// 捕获任何 panic 并转换为错误返回
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("发生 panic: %v", r)
+			result = buildErrorResult(fmt.Sprintf("内部错误: %v", r))
+			err = nil
+		}
+	}()
+
+	log.Printf("收到 linter 说明请求: name=%s args=%v", req.Params.Name, req.Params.Arguments)
+
+	var explainReq LintExplainRequest
+	if req.Params.Arguments == nil {
+		req.Params.Arguments = map[string]interface{}{}
+	}
+	argsBytes, _ := json.Marshal(req.Params.Arguments)
+	if err := json.Unmarshal(argsBytes, &explainReq); err != nil {
+		return buildErrorResult(fmt.Sprintf("无效的请求参数: %v", err)), nil
+	}
+	if strings.TrimSpace(explainReq.Linter) == "" {
+		return buildErrorResult("缺少参数 linter"), nil
+	}
+
+	explain, err := explainLinter(explainReq.Linter, explainReq.Rule, explainReq.Message)
+	if err != nil {
+		return buildErrorResult(err.Error()), nil
+	}
+	resultJSON, _ := json.Marshal(explain)
+	return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Type: "text", Text: string(resultJSON)}}}, nil
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 问题指纹：linter + 规范化路径（相对模块根目录，正斜杠）+ 描述 + 问题所在代码行的哈希。
+// 不包含行号，代码上下移动时指纹不变；问题所在行被修改时视为新问题。
+// 完全相同的代码行上的同类问题共享同一指纹，客户端跨运行比较时应按指纹计数
+
+// sourceLineCache 缓存已读取的源文件，同一文件的多个问题只读一次
+type sourceLineCache map[string][]string
+
+// line 返回文件第 n 行（从 1 开始）去除首尾空白后的内容，读取失败时返回空字符串
+func (c sourceLineCache) line(path string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	lines, ok := c[path]
+	if !ok {
+		if f, err := os.Open(path); err == nil {
+			scanner := bufio.NewScanner(f)
+			scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+			for scanner.Scan() {
+				lines = append(lines, scanner.Text())
+			}
+			f.Close()
+		}
+		c[path] = lines
+	}
+	if n > len(lines) {
+		return ""
+	}
+	return strings.TrimSpace(lines[n-1])
+}
+
+// fingerprintIssues 为问题计算指纹；projectRoot 为 golangci-lint 的运行目录，用于解析相对路径
+func fingerprintIssues(projectRoot string, issues []Issue) []Issue {
+	cache := make(sourceLineCache)
+	for i := range issues {
+		issues[i].Fingerprint, issues[i].absPath = issueFingerprint(projectRoot, issues[i], cache)
+	}
+	return issues
+}
+
+// issueFingerprint 计算单个问题的指纹，同时返回问题文件的绝对路径（无法确定时为空）；
+// projectRoot 为空时按文件所在的 Go 模块确定相对路径
+func issueFingerprint(projectRoot string, issue Issue, cache sourceLineCache) (string, string) {
+	path, relPath := normalizeIssuePath(projectRoot, issue.Pos.Filename)
+	code := ""
+	if path != "" {
+		code = cache.line(path, issue.Pos.Line)
+	}
+	codeHash := sha256.Sum256([]byte(code))
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%s\x00%s\x00%x", issue.FromLinter, relPath, strings.TrimSpace(issue.Text), codeHash)))
+	return hex.EncodeToString(sum[:8]), path
+}
+
+// normalizeIssuePath 返回问题文件的绝对路径（无法确定时为空）以及用于指纹的相对路径
+func normalizeIssuePath(projectRoot, filename string) (string, string) {
+	if filename == "" {
+		return "", ""
+	}
+	abs := filename
+	if !filepath.IsAbs(abs) {
+		if projectRoot == "" {
+			return "", filepath.ToSlash(filepath.Clean(filename))
+		}
+		abs = filepath.Join(projectRoot, abs)
+	}
+	abs = filepath.Clean(abs)
+	root := projectRoot
+	if root == "" {
+		root, _ = findGoModRoot(filepath.Dir(abs))
+	}
+	if root != "" {
+		if rel, err := filepath.Rel(root, abs); err == nil && isWithinDir(root, abs) {
+			return abs, filepath.ToSlash(rel)
+		}
+	}
+	return abs, filepath.ToSlash(abs)
+}
+
+// issueAbsPath 返回问题文件的绝对路径：优先使用计算指纹时确定的路径，否则相对 projectRoot 解析
+func issueAbsPath(projectRoot string, issue Issue) string {
+	if issue.absPath != "" {
+		return issue.absPath
+	}
+	if issue.Pos.Filename == "" || filepath.IsAbs(issue.Pos.Filename) {
+		return filepath.Clean(issue.Pos.Filename)
+	}
+	return filepath.Join(projectRoot, issue.Pos.Filename)
+}
+
+// dedupeKey 去重时识别同一问题：指纹与位置相同，且位于同一文件。
+// 指纹使用模块内的相对路径，不同模块中的同名文件需按绝对路径区分；绝对路径未知时（如从 JSON 结果解析的问题）只比较指纹与位置
+func dedupeKey(issue Issue) string {
+	return fmt.Sprintf("%s\x00%s\x00%d\x00%d", issue.absPath, issue.Fingerprint, issue.Pos.Line, issue.Pos.Column)
+}
+
+// dedupeIssues 去除重复问题（如逐文件检查时同一包级问题被多次报告），保留首次出现的问题，
+// 并合并重复问题的 Configurations；缺少指纹的问题（如编译错误）先补算指纹
+func dedupeIssues(issues []Issue) []Issue {
+	cache := make(sourceLineCache)
+	seen := make(map[string]int, len(issues))
+	deduped := make([]Issue, 0, len(issues))
+	for _, issue := range issues {
+		if issue.Fingerprint == "" {
+			issue.Fingerprint, issue.absPath = issueFingerprint("", issue, cache)
+		}
+		key := dedupeKey(issue)
+		if i, ok := seen[key]; ok {
+			deduped[i].Configurations = mergeConfigurations(deduped[i].Configurations, issue.Configurations)
+			continue
+		}
+		seen[key] = len(deduped)
+		deduped = append(deduped, issue)
+	}
+	if dropped := len(issues) - len(deduped); dropped > 0 {
+		log.Printf("去除 %d 个重复问题", dropped)
+	}
+	return deduped
+}
+
+// mergeConfigurations 将 extra 中尚未出现的构建配置追加到 configs
+func mergeConfigurations(configs, extra []string) []string {
+	for _, label := range extra {
+		if !containsString(configs, label) {
+			configs = append(configs, label)
+		}
+	}
+	return configs
+}
@@ -0,0 +1,366 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/scanner"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"golang.org/x/tools/imports"
+)
+
+// CodeFormatRequest 定义格式检查请求结构
+type CodeFormatRequest struct {
+	Files            []string `json:"files" description:"待检查的文件列表（可选）。checkOnlyChanges=false 时优先检查这些文件"`
+	ProjectPath      string   `json:"projectPath" description:"项目根目录（可选，优先作为检测起点）"`
+	CheckOnlyChanges bool     `json:"checkOnlyChanges" description:"是否只检查变更文件（默认true）" default:"true"`
+	Write            bool     `json:"write" description:"是否将格式化结果原地写回文件（默认false）"`
+	IncludeGenerated bool     `json:"includeGenerated" description:"是否包含生成代码（带 \"Code generated ... DO NOT EDIT.\" 标记的文件，默认false）"`
+	TrunkBranchesArg
+}
+
+// FormatResult 表示格式检查结果，Issues 与 LintResult 保持一致，便于上层统一处理
+type FormatResult struct {
+	Issues []Issue           `json:"Issues"`
+	Files  []FormatFileEntry `json:"Files"`
+	Scope  *ScopeInfo        `json:"Scope,omitempty"`
+	// SkippedGenerated 因生成代码而跳过的文件数
+	SkippedGenerated int `json:"SkippedGenerated,omitempty"`
+}
+
+// FormatFileEntry 表示单个未格式化文件的差异
+type FormatFileEntry struct {
+	Filename  string `json:"Filename"`
+	Diff      string `json:"Diff"`
+	Rewritten bool   `json:"Rewritten"`
+}
+
+// formatGoFile 按 goimports 规则格式化单个文件：gofmt 格式化、补全缺失的 import、删除未使用的 import 并分组排序
+// 返回原始内容、格式化后内容；解析失败时返回错误
+func formatGoFile(file string) ([]byte, []byte, error) {
+	src, err := os.ReadFile(file)
+	if err != nil {
+		return nil, nil, fmt.Errorf("读取文件失败: %v", err)
+	}
+	formatted, err := imports.Process(file, src, nil)
+	if err != nil {
+		return src, nil, err
+	}
+	return src, formatted, nil
+}
+
+// checkFormat 检查文件列表的格式，必要时原地改写
+func checkFormat(files []string, write bool) *FormatResult {
+	result := &FormatResult{Issues: make([]Issue, 0), Files: make([]FormatFileEntry, 0)}
+
+	for _, file := range files {
+		src, formatted, err := formatGoFile(file)
+		if err != nil {
+			log.Printf("格式化文件 %s 失败: %v", file, err)
+			pos := Pos{Filename: file}
+			if list, ok := err.(scanner.ErrorList); ok && len(list) > 0 {
+				pos.Line = list[0].Pos.Line
+				pos.Column = list[0].Pos.Column
+			}
+			result.Issues = append(result.Issues, Issue{
+				FromLinter: "goimports",
+				Text:       fmt.Sprintf("无法格式化（语法错误）: %v", err),
+				Severity:   "error",
+				Pos:        pos,
+			})
+			continue
+		}
+
+		if bytes.Equal(src, formatted) {
+			continue
+		}
+
+		oldLines := splitLines(string(src))
+		newLines := splitLines(string(formatted))
+		diff := unifiedDiff(file, file, oldLines, newLines)
+		entry := FormatFileEntry{Filename: file, Diff: diff}
+
+		if write {
+			info, statErr := os.Stat(file)
+			perm := os.FileMode(0644)
+			if statErr == nil {
+				perm = info.Mode().Perm()
+			}
+			if err := os.WriteFile(file, formatted, perm); err != nil {
+				log.Printf("写回文件 %s 失败: %v", file, err)
+				result.Issues = append(result.Issues, Issue{
+					FromLinter: "goimports",
+					Text:       fmt.Sprintf("写回格式化结果失败: %v", err),
+					Severity:   "error",
+					Pos:        Pos{Filename: file},
+				})
+			} else {
+				entry.Rewritten = true
+				log.Printf("已格式化并写回文件: %s", file)
+			}
+		}
+		result.Files = append(result.Files, entry)
+
+		text := "文件未按 goimports 格式化"
+		if entry.Rewritten {
+			text = "文件未按 goimports 格式化（已自动改写）"
+		}
+		result.Issues = append(result.Issues, Issue{
+			FromLinter: "goimports",
+			Text:       text,
+			Severity:   "warning",
+			Pos:        Pos{Filename: file, Line: firstDiffLine(oldLines, newLines), Column: 1},
+		})
+	}
+
+	return result
+}
+
+// splitLines 按行切分文本，不保留换行符
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.Split(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// firstDiffLine 返回第一处差异所在的行号（从1开始）
+func firstDiffLine(a, b []string) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return i + 1
+		}
+	}
+	if len(a) < len(b) {
+		return len(a) + 1
+	}
+	return len(b) + 1
+}
+
+// diffOp 表示差异中的一行：' ' 不变，'-' 删除，'+' 新增
+type diffOp struct {
+	kind byte
+	text string
+	aIdx int
+	bIdx int
+}
+
+// maxDiffCells 限制 LCS 计算的矩阵规模，超出时退化为整段替换
+const maxDiffCells = 4 << 20
+
+// diffLines 基于 LCS 计算两组行之间的差异
+func diffLines(a, b []string) []diffOp {
+	// 先去掉公共前后缀，格式化差异通常只集中在少数位置
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ops := make([]diffOp, 0, len(a)+len(b))
+	for i := 0; i < prefix; i++ {
+		ops = append(ops, diffOp{kind: ' ', text: a[i], aIdx: i, bIdx: i})
+	}
+
+	midA := a[prefix : len(a)-suffix]
+	midB := b[prefix : len(b)-suffix]
+	n, m := len(midA), len(midB)
+
+	if n*m > maxDiffCells {
+		for i, line := range midA {
+			ops = append(ops, diffOp{kind: '-', text: line, aIdx: prefix + i, bIdx: prefix})
+		}
+		for j, line := range midB {
+			ops = append(ops, diffOp{kind: '+', text: line, aIdx: prefix + n, bIdx: prefix + j})
+		}
+	} else {
+		// lcs[i][j] 表示 midA[i:] 与 midB[j:] 的最长公共子序列长度
+		lcs := make([][]int, n+1)
+		for i := range lcs {
+			lcs[i] = make([]int, m+1)
+		}
+		for i := n - 1; i >= 0; i-- {
+			for j := m - 1; j >= 0; j-- {
+				if midA[i] == midB[j] {
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				} else if lcs[i+1][j] >= lcs[i][j+1] {
+					lcs[i][j] = lcs[i+1][j]
+				} else {
+					lcs[i][j] = lcs[i][j+1]
+				}
+			}
+		}
+		i, j := 0, 0
+		for i < n || j < m {
+			switch {
+			case i < n && j < m && midA[i] == midB[j]:
+				ops = append(ops, diffOp{kind: ' ', text: midA[i], aIdx: prefix + i, bIdx: prefix + j})
+				i++
+				j++
+			case j < m && (i == n || lcs[i][j+1] > lcs[i+1][j]):
+				ops = append(ops, diffOp{kind: '+', text: midB[j], aIdx: prefix + i, bIdx: prefix + j})
+				j++
+			default:
+				ops = append(ops, diffOp{kind: '-', text: midA[i], aIdx: prefix + i, bIdx: prefix + j})
+				i++
+			}
+		}
+	}
+
+	for k := 0; k < suffix; k++ {
+		ai := len(a) - suffix + k
+		bi := len(b) - suffix + k
+		ops = append(ops, diffOp{kind: ' ', text: a[ai], aIdx: ai, bIdx: bi})
+	}
+	return ops
+}
+
+// unifiedDiff 生成 unified diff 格式文本（上下文3行）
+func unifiedDiff(oldName, newName string, a, b []string) string {
+	const contextLines = 3
+	ops := diffLines(a, b)
+
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "--- %s\n+++ %s\n", oldName, newName)
+
+	for start := 0; start < len(ops); {
+		// 找到下一处变更
+		for start < len(ops) && ops[start].kind == ' ' {
+			start++
+		}
+		if start >= len(ops) {
+			break
+		}
+
+		hunkStart := start - contextLines
+		if hunkStart < 0 {
+			hunkStart = 0
+		}
+		// 向后扩展，直到连续不变的行超过 2*contextLines
+		end := start
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].kind == ' ' {
+				run++
+			}
+			if run == len(ops) || run-end > 2*contextLines {
+				end += contextLines
+				if end > run {
+					end = run
+				}
+				break
+			}
+			end = run
+		}
+
+		hunk := ops[hunkStart:end]
+		aStart, bStart := hunk[0].aIdx+1, hunk[0].bIdx+1
+		aCount, bCount := 0, 0
+		for _, op := range hunk {
+			if op.kind != '+' {
+				aCount++
+			}
+			if op.kind != '-' {
+				bCount++
+			}
+		}
+		if aCount == 0 {
+			aStart--
+		}
+		if bCount == 0 {
+			bStart--
+		}
+		fmt.Fprintf(&buf, "@@ -%d,%d +%d,%d @@\n", aStart, aCount, bStart, bCount)
+		for _, op := range hunk {
+			buf.WriteByte(op.kind)
+			buf.WriteString(op.text)
+			buf.WriteByte('\n')
+		}
+		start = end
+	}
+	return buf.String()
+}
+
+// handleCodeFormatRequest 处理格式检查请求
+func handleCodeFormatRequest(ctx context.Context, req mcp.CallToolRequest) (result *mcp.CallToolResult, err error) {
+	// 捕获任何 panic 并转换为错误返回
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("发生 panic: %v", r)
+			result = buildErrorResult(fmt.Sprintf("内部错误: %v", r))
+			err = nil
+		}
+	}()
+
+	log.Printf("收到格式检查请求: name=%s args=%v", req.Params.Name, req.Params.Arguments)
+
+	var formatReq CodeFormatRequest
+	if req.Params.Arguments == nil {
+		req.Params.Arguments = map[string]interface{}{}
+	}
+	cfg := applyToolDefaults("code_format", req.Params.Arguments)
+	if !cfg.backendEnabled(backendGofmt) {
+		return backendDisabledResult(backendGofmt, cfg), nil
+	}
+	argsBytes, _ := json.Marshal(req.Params.Arguments)
+	if err := json.Unmarshal(argsBytes, &formatReq); err != nil {
+		return buildErrorResult(fmt.Sprintf("无效的请求参数: %v", err)), nil
+	}
+	if _, exists := req.Params.Arguments["checkOnlyChanges"]; !exists {
+		formatReq.CheckOnlyChanges = true
+	}
+
+	baseDir, err := resolveBaseDir(formatReq.ProjectPath, formatReq.Files)
+	if err != nil {
+		return buildPathErrorResult(err), nil
+	}
+	log.Printf("格式检查起点目录: %s", baseDir)
+
+	var files []string
+	var scope *ScopeInfo
+	var skippedGenerated int
+	if formatReq.CheckOnlyChanges {
+		changeSet, err := getChangeSet(baseDir, ScopeOptions{TrunkBranches: formatReq.TrunkBranches, Config: cfg, SkipGenerated: !formatReq.IncludeGenerated})
+		if err != nil {
+			log.Printf("未检测到变更文件: %v", err)
+		} else {
+			files = changeSet.Paths()
+			scope = changeSet.Scope()
+			skippedGenerated = len(changeSet.SkippedGenerated)
+		}
+	} else {
+		for _, f := range formatReq.Files {
+			if strings.HasSuffix(f, ".go") {
+				files = append(files, f)
+			}
+		}
+		if len(files) == 0 {
+			files, skippedGenerated, err = findAllGoFiles(baseDir, cfg, formatReq.IncludeGenerated, testsInclude)
+			if err != nil {
+				return buildErrorResult(fmt.Sprintf("扫描Go文件失败: %v", err)), nil
+			}
+		}
+	}
+
+	log.Printf("格式检查 %d 个文件，write=%v", len(files), formatReq.Write)
+	formatResult := checkFormat(files, formatReq.Write)
+	formatResult.Scope = scope
+	formatResult.SkippedGenerated = skippedGenerated
+	return buildToolResult(formatResult, cfg), nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// generatedHeaderRegex Go 约定的生成代码标记（https://go.dev/s/generatedcode）
+var generatedHeaderRegex = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+
+// hasGeneratedHeader 判断文件在 package 子句之前是否带有生成代码标记行
+// 标记必须是独立的 // 行注释，块注释中的同样文本不算
+func hasGeneratedHeader(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	inBlock := false
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		trimmed := strings.TrimSpace(line)
+		if inBlock {
+			if idx := strings.Index(trimmed, "*/"); idx >= 0 {
+				inBlock = false
+				trimmed = strings.TrimSpace(trimmed[idx+2:])
+				if trimmed == "" {
+					continue
+				}
+			} else {
+				continue
+			}
+		}
+		if generatedHeaderRegex.MatchString(line) {
+			return true
+		}
+		switch {
+		case trimmed == "", strings.HasPrefix(trimmed, "//"):
+			continue
+		case strings.HasPrefix(trimmed, "/*"):
+			if !strings.Contains(trimmed[2:], "*/") {
+				inBlock = true
+			}
+			continue
+		}
+		// 遇到 package 子句或其他代码即停止
+		return false
+	}
+	return false
+}
+
+// isGeneratedGoFile 判断文件是否为生成代码：带有标准生成标记，或文件名匹配配置的 generatedPatterns
+func isGeneratedGoFile(path string, cfg *EffectiveConfig) bool {
+	if cfg != nil && cfg.isGeneratedName(path) {
+		return true
+	}
+	return hasGeneratedHeader(path)
+}
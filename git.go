@@ -0,0 +1,365 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// GitRepo 封装变更检测所需的全部 Git 操作
+// 路径参数与返回值均相对于仓库顶层目录（斜杠分隔），Dir 为命令执行目录
+type GitRepo interface {
+	// Dir 返回仓库操作的起点目录
+	Dir() string
+	// TopLevel 返回仓库顶层目录的绝对路径
+	TopLevel() (string, error)
+	// CurrentBranch 返回当前分支名，分离 HEAD 时返回空字符串
+	CurrentBranch() (string, error)
+	// RevExists 判断修订（分支、标签、提交等）是否存在
+	RevExists(rev string) bool
+	// CountCommits 统计 from..to 范围内的提交数
+	CountCommits(from, to string) (int, error)
+	// MergeBase 返回两个修订的合并基点
+	MergeBase(a, b string) (string, error)
+	// DefaultRemoteBranch 返回 origin 的默认分支（如 "origin/main"）
+	DefaultRemoteBranch() (string, error)
+	// Branches 返回本地分支与远程跟踪分支（不含 origin/HEAD 等符号引用）
+	Branches() ([]GitBranch, error)
+	// Status 返回工作区状态（git status --porcelain=v2）
+	Status() (*GitStatus, error)
+	// DiffNameStatus 返回 rev 与工作区之间、起点目录下的文件变更（含重命名检测）
+	DiffNameStatus(rev string) ([]GitDiffEntry, error)
+	// UntrackedFiles 返回起点目录下未被忽略的未跟踪文件
+	UntrackedFiles() ([]string, error)
+	// ShowFile 返回文件在指定修订中的内容
+	ShowFile(rev, path string) ([]byte, error)
+	// Submodules 返回已初始化的子模块路径（递归）
+	Submodules() ([]string, error)
+}
+
+// GitBranch 表示一个本地分支或远程跟踪分支
+type GitBranch struct {
+	Name   string // 去掉远程名后的分支名，如 "release/1.2"
+	Remote string // 远程名，本地分支为空
+}
+
+// ShortName 返回分支短名，如 "main"、"origin/release/1.2"
+func (b GitBranch) ShortName() string {
+	if b.Remote == "" {
+		return b.Name
+	}
+	return b.Remote + "/" + b.Name
+}
+
+// GitStatus 表示 git status --porcelain=v2 --branch 的解析结果
+type GitStatus struct {
+	Branch   string // 当前分支，分离 HEAD 时为 "(detached)"
+	Upstream string // 上游分支，未设置时为空
+	Ahead    int
+	Behind   int
+	Entries  []GitStatusEntry
+}
+
+// GitStatusEntry 表示工作区中的一个变更条目
+type GitStatusEntry struct {
+	Kind     byte   // '1' 普通变更、'2' 重命名/复制、'u' 冲突、'?' 未跟踪
+	XY       string // 暂存区与工作区状态，如 ".M"、"A."
+	Path     string
+	OrigPath string // 重命名/复制前的路径
+}
+
+// GitDiffEntry 表示 git diff --name-status 的一条记录
+type GitDiffEntry struct {
+	Status     string // A、M、D、R、C（T 归为 M）
+	Similarity int
+	Path       string
+	OldPath    string
+}
+
+// newGitRepo 创建 Git 仓库访问对象；测试中可覆盖该变量替换为 fakeGitRepo
+var newGitRepo = func(dir string) GitRepo {
+	return &cliGitRepo{dir: dir}
+}
+
+// cliGitRepo 基于 git 命令行的 GitRepo 实现，统一使用 -z / porcelain v2 等机器可读格式
+type cliGitRepo struct {
+	dir      string
+	topLevel string
+}
+
+// run 在仓库起点目录执行 git 命令，返回标准输出
+func (r *cliGitRepo) run(args ...string) ([]byte, error) {
+	return r.runIn(r.dir, args...)
+}
+
+// runIn 在指定目录执行 git 命令（如需要输出路径相对于仓库顶层时在顶层目录执行），返回标准输出
+func (r *cliGitRepo) runIn(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			return out, fmt.Errorf("git %s: %v", strings.Join(args, " "), err)
+		}
+		return out, fmt.Errorf("git %s: %v: %s", strings.Join(args, " "), err, msg)
+	}
+	return out, nil
+}
+
+// runLine 执行 git 命令并返回去除首尾空白的单行输出
+func (r *cliGitRepo) runLine(args ...string) (string, error) {
+	out, err := r.run(args...)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func (r *cliGitRepo) Dir() string {
+	return r.dir
+}
+
+func (r *cliGitRepo) TopLevel() (string, error) {
+	if r.topLevel != "" {
+		return r.topLevel, nil
+	}
+	top, err := r.runLine("rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", err
+	}
+	r.topLevel = filepath.Clean(top)
+	return r.topLevel, nil
+}
+
+func (r *cliGitRepo) CurrentBranch() (string, error) {
+	out, err := r.run("symbolic-ref", "--short", "-q", "HEAD")
+	if err != nil {
+		// 分离 HEAD 时 symbolic-ref -q 以非零状态退出且无输出
+		if len(out) == 0 {
+			if _, verr := r.run("rev-parse", "--verify", "-q", "HEAD"); verr == nil {
+				return "", nil
+			}
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func (r *cliGitRepo) RevExists(rev string) bool {
+	_, err := r.run("rev-parse", "--verify", "-q", rev+"^{commit}")
+	return err == nil
+}
+
+func (r *cliGitRepo) CountCommits(from, to string) (int, error) {
+	out, err := r.runLine("rev-list", "--count", from+".."+to)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(out)
+}
+
+func (r *cliGitRepo) MergeBase(a, b string) (string, error) {
+	return r.runLine("merge-base", a, b)
+}
+
+func (r *cliGitRepo) DefaultRemoteBranch() (string, error) {
+	// 输出如 "origin/main"
+	return r.runLine("symbolic-ref", "--short", "-q", "refs/remotes/origin/HEAD")
+}
+
+func (r *cliGitRepo) Branches() ([]GitBranch, error) {
+	// 每行为 "<完整引用名>\x00<符号引用目标>"
+	out, err := r.run("for-each-ref", "--format=%(refname)%00%(symref)", "refs/heads", "refs/remotes")
+	if err != nil {
+		return nil, err
+	}
+	return parseBranchRefs(string(out)), nil
+}
+
+func (r *cliGitRepo) Status() (*GitStatus, error) {
+	out, err := r.run("status", "--porcelain=v2", "--branch", "-z", "--untracked-files=all")
+	if err != nil {
+		return nil, err
+	}
+	return parseStatusV2Z(string(out)), nil
+}
+
+func (r *cliGitRepo) DiffNameStatus(rev string) ([]GitDiffEntry, error) {
+	out, err := r.run("diff", "--name-status", "-M", "-z", rev, "--", ".")
+	if err != nil {
+		return nil, err
+	}
+	return parseNameStatusZ(string(out)), nil
+}
+
+func (r *cliGitRepo) UntrackedFiles() ([]string, error) {
+	out, err := r.run("ls-files", "--others", "--exclude-standard", "--full-name", "-z", "--", ".")
+	if err != nil {
+		return nil, err
+	}
+	return splitNul(string(out)), nil
+}
+
+func (r *cliGitRepo) ShowFile(rev, path string) ([]byte, error) {
+	// rev:path 中的路径相对于仓库顶层目录
+	return r.run("show", rev+":"+path)
+}
+
+func (r *cliGitRepo) Submodules() ([]string, error) {
+	top, err := r.TopLevel()
+	if err != nil {
+		return nil, err
+	}
+	// 在顶层目录执行，使输出路径相对于仓库顶层；格式为 "[ +-U]<sha> <路径> (<描述>)"
+	out, err := r.runIn(top, "submodule", "status", "--recursive")
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if len(line) < 2 || line[0] == '-' {
+			continue
+		}
+		fields := strings.Fields(line[1:])
+		if len(fields) >= 2 {
+			paths = append(paths, fields[1])
+		}
+	}
+	return paths, nil
+}
+
+// splitNul 按 NUL 拆分 -z 格式输出并去除空项
+func splitNul(output string) []string {
+	var items []string
+	for _, item := range strings.Split(output, "\x00") {
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseNameStatusZ 解析 git diff --name-status -z 的输出
+// 每条记录为 "状态\0路径\0"，重命名/复制为 "R相似度\0旧路径\0新路径\0"
+func parseNameStatusZ(output string) []GitDiffEntry {
+	var entries []GitDiffEntry
+	fields := strings.Split(output, "\x00")
+	for i := 0; i < len(fields); i++ {
+		status := strings.TrimSpace(fields[i])
+		if status == "" {
+			continue
+		}
+		kind := status[:1]
+		if kind == "R" || kind == "C" {
+			// 不完整的记录（缺少新路径）直接丢弃，不能当作普通记录解析
+			if i+2 < len(fields) {
+				similarity, _ := strconv.Atoi(status[1:])
+				entries = append(entries, GitDiffEntry{OldPath: fields[i+1], Path: fields[i+2], Status: kind, Similarity: similarity})
+			}
+			i += 2
+			continue
+		}
+		if i+1 < len(fields) {
+			if kind == "T" {
+				kind = "M"
+			}
+			entries = append(entries, GitDiffEntry{Path: fields[i+1], Status: kind})
+			i++
+		}
+	}
+	return entries
+}
+
+// parseStatusV2Z 解析 git status --porcelain=v2 --branch -z 的输出
+func parseStatusV2Z(output string) *GitStatus {
+	status := &GitStatus{}
+	fields := strings.Split(output, "\x00")
+	for i := 0; i < len(fields); i++ {
+		line := fields[i]
+		if line == "" {
+			continue
+		}
+		switch line[0] {
+		case '#':
+			// "# branch.head <name>"、"# branch.upstream <name>"、"# branch.ab +N -M"
+			parts := strings.Fields(line)
+			if len(parts) < 3 {
+				continue
+			}
+			switch parts[1] {
+			case "branch.head":
+				status.Branch = parts[2]
+			case "branch.upstream":
+				status.Upstream = parts[2]
+			case "branch.ab":
+				if len(parts) >= 4 {
+					status.Ahead, _ = strconv.Atoi(strings.TrimPrefix(parts[2], "+"))
+					status.Behind, _ = strconv.Atoi(strings.TrimPrefix(parts[3], "-"))
+				}
+			}
+		case '1':
+			// 1 <XY> <sub> <mH> <mI> <mW> <hH> <hI> <path>
+			parts := strings.SplitN(line, " ", 9)
+			if len(parts) == 9 {
+				status.Entries = append(status.Entries, GitStatusEntry{Kind: '1', XY: parts[1], Path: parts[8]})
+			}
+		case '2':
+			// 2 <XY> <sub> <mH> <mI> <mW> <hH> <hI> <X><score> <path>\0<origPath>
+			parts := strings.SplitN(line, " ", 10)
+			if len(parts) == 10 {
+				entry := GitStatusEntry{Kind: '2', XY: parts[1], Path: parts[9]}
+				if i+1 < len(fields) {
+					entry.OrigPath = fields[i+1]
+					i++
+				}
+				status.Entries = append(status.Entries, entry)
+			}
+		case 'u':
+			// u <XY> <sub> <m1> <m2> <m3> <mW> <h1> <h2> <h3> <path>
+			parts := strings.SplitN(line, " ", 11)
+			if len(parts) == 11 {
+				status.Entries = append(status.Entries, GitStatusEntry{Kind: 'u', XY: parts[1], Path: parts[10]})
+			}
+		case '?':
+			status.Entries = append(status.Entries, GitStatusEntry{Kind: '?', XY: "??", Path: strings.TrimPrefix(line, "? ")})
+		}
+	}
+	return status
+}
+
+// parseBranchRefs 解析 for-each-ref 输出的分支引用，跳过符号引用
+// 远程名取 refs/remotes/ 之后的第一段
+func parseBranchRefs(output string) []GitBranch {
+	var branches []GitBranch
+	for _, line := range strings.Split(output, "\n") {
+		parts := strings.SplitN(line, "\x00", 2)
+		if parts[0] == "" || (len(parts) == 2 && parts[1] != "") {
+			continue
+		}
+		ref := parts[0]
+		switch {
+		case strings.HasPrefix(ref, "refs/heads/"):
+			branches = append(branches, GitBranch{Name: strings.TrimPrefix(ref, "refs/heads/")})
+		case strings.HasPrefix(ref, "refs/remotes/"):
+			rest := strings.TrimPrefix(ref, "refs/remotes/")
+			if idx := strings.Index(rest, "/"); idx > 0 {
+				branches = append(branches, GitBranch{Remote: rest[:idx], Name: rest[idx+1:]})
+			}
+		}
+	}
+	return branches
+}
+
+// logGitError 记录 git 操作失败（不影响后续策略）
+func logGitError(op string, err error) {
+	if err != nil {
+		log.Printf("%s 失败: %v", op, err)
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseNameStatusZ(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   []GitDiffEntry
+	}{
+		{
+			name:   "空输出",
+			output: "",
+			want:   nil,
+		},
+		{
+			name:   "新增修改删除",
+			output: "A\x00a.go\x00M\x00pkg/b.go\x00D\x00c.go\x00",
+			want: []GitDiffEntry{
+				{Status: "A", Path: "a.go"},
+				{Status: "M", Path: "pkg/b.go"},
+				{Status: "D", Path: "c.go"},
+			},
+		},
+		{
+			name:   "类型变更归为修改",
+			output: "T\x00link.go\x00",
+			want:   []GitDiffEntry{{Status: "M", Path: "link.go"}},
+		},
+		{
+			name:   "重命名与复制带相似度",
+			output: "R087\x00old/a.go\x00new/a.go\x00C100\x00b.go\x00b_copy.go\x00M\x00c.go\x00",
+			want: []GitDiffEntry{
+				{Status: "R", Similarity: 87, OldPath: "old/a.go", Path: "new/a.go"},
+				{Status: "C", Similarity: 100, OldPath: "b.go", Path: "b_copy.go"},
+				{Status: "M", Path: "c.go"},
+			},
+		},
+		{
+			name:   "路径含空格与换行",
+			output: "M\x00dir name/a b.go\x00A\x00line\nbreak.go\x00",
+			want: []GitDiffEntry{
+				{Status: "M", Path: "dir name/a b.go"},
+				{Status: "A", Path: "line\nbreak.go"},
+			},
+		},
+		{
+			name:   "截断的重命名记录被忽略",
+			output: "R090\x00old.go",
+			want:   nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseNameStatusZ(tt.output); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseNameStatusZ() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseStatusV2Z(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   *GitStatus
+	}{
+		{
+			name:   "干净的工作区",
+			output: "# branch.oid 1234567890abcdef\x00# branch.head main\x00# branch.upstream origin/main\x00# branch.ab +0 -0\x00",
+			want:   &GitStatus{Branch: "main", Upstream: "origin/main"},
+		},
+		{
+			name:   "分离 HEAD 且无上游",
+			output: "# branch.oid 1234567890abcdef\x00# branch.head (detached)\x00",
+			want:   &GitStatus{Branch: "(detached)"},
+		},
+		{
+			name: "普通变更与未跟踪文件",
+			output: "# branch.head feat\x00# branch.upstream origin/feat\x00# branch.ab +2 -1\x00" +
+				"1 .M N... 100644 100644 100644 aaaa bbbb a.go\x00" +
+				"1 A. N... 000000 100644 100644 0000 cccc dir name/b.go\x00" +
+				"? new.go\x00",
+			want: &GitStatus{
+				Branch: "feat", Upstream: "origin/feat", Ahead: 2, Behind: 1,
+				Entries: []GitStatusEntry{
+					{Kind: '1', XY: ".M", Path: "a.go"},
+					{Kind: '1', XY: "A.", Path: "dir name/b.go"},
+					{Kind: '?', XY: "??", Path: "new.go"},
+				},
+			},
+		},
+		{
+			name:   "重命名带原路径",
+			output: "# branch.head main\x002 R. N... 100644 100644 100644 aaaa bbbb R100 new.go\x00old.go\x00",
+			want: &GitStatus{
+				Branch:  "main",
+				Entries: []GitStatusEntry{{Kind: '2', XY: "R.", Path: "new.go", OrigPath: "old.go"}},
+			},
+		},
+		{
+			name:   "冲突",
+			output: "u UU N... 100644 100644 100644 100644 aaaa bbbb cccc conflict.go\x00",
+			want:   &GitStatus{Entries: []GitStatusEntry{{Kind: 'u', XY: "UU", Path: "conflict.go"}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseStatusV2Z(tt.output); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseStatusV2Z() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
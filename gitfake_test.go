@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+)
+
+// fakeGitRepo 内存中的 GitRepo 实现，用于在不依赖真实仓库的情况下验证变更检测逻辑
+// 替换方式：newGitRepo = func(dir string) GitRepo { return fake }
+type fakeGitRepo struct {
+	DirPath       string
+	Top           string
+	Branch        string            // 当前分支，空表示分离 HEAD
+	Refs          map[string]string // 修订名 -> 提交哈希
+	Counts        map[string]int    // "from..to" -> 提交数
+	MergeBases    map[string]string // "a...b" -> 合并基点
+	DefaultRemote string
+	BranchList    []GitBranch
+	WorkStatus    GitStatus
+	Diffs         map[string][]GitDiffEntry // 修订 -> 与工作区的差异
+	Untracked     []string
+	Files         map[string][]byte // "rev:path" -> 内容
+	SubmodulePath []string
+}
+
+func (f *fakeGitRepo) Dir() string {
+	return f.DirPath
+}
+
+func (f *fakeGitRepo) TopLevel() (string, error) {
+	if f.Top == "" {
+		return filepath.Clean(f.DirPath), nil
+	}
+	return filepath.Clean(f.Top), nil
+}
+
+func (f *fakeGitRepo) CurrentBranch() (string, error) {
+	return f.Branch, nil
+}
+
+func (f *fakeGitRepo) RevExists(rev string) bool {
+	_, ok := f.Refs[rev]
+	return ok
+}
+
+func (f *fakeGitRepo) CountCommits(from, to string) (int, error) {
+	n, ok := f.Counts[from+".."+to]
+	if !ok {
+		return 0, fmt.Errorf("fake: 未知范围 %s..%s", from, to)
+	}
+	return n, nil
+}
+
+func (f *fakeGitRepo) MergeBase(a, b string) (string, error) {
+	if base, ok := f.MergeBases[a+"..."+b]; ok {
+		return base, nil
+	}
+	if base, ok := f.MergeBases[b+"..."+a]; ok {
+		return base, nil
+	}
+	return "", fmt.Errorf("fake: %s 与 %s 没有合并基点", a, b)
+}
+
+func (f *fakeGitRepo) DefaultRemoteBranch() (string, error) {
+	if f.DefaultRemote == "" {
+		return "", fmt.Errorf("fake: 未设置 origin/HEAD")
+	}
+	return f.DefaultRemote, nil
+}
+
+func (f *fakeGitRepo) Branches() ([]GitBranch, error) {
+	return f.BranchList, nil
+}
+
+func (f *fakeGitRepo) Status() (*GitStatus, error) {
+	status := f.WorkStatus
+	return &status, nil
+}
+
+func (f *fakeGitRepo) DiffNameStatus(rev string) ([]GitDiffEntry, error) {
+	if rev != "HEAD" && !f.RevExists(rev) {
+		return nil, fmt.Errorf("fake: 未知修订 %s", rev)
+	}
+	return f.Diffs[rev], nil
+}
+
+func (f *fakeGitRepo) UntrackedFiles() ([]string, error) {
+	return f.Untracked, nil
+}
+
+func (f *fakeGitRepo) ShowFile(rev, path string) ([]byte, error) {
+	content, ok := f.Files[rev+":"+path]
+	if !ok {
+		return nil, fmt.Errorf("fake: %s:%s 不存在", rev, path)
+	}
+	return content, nil
+}
+
+func (f *fakeGitRepo) Submodules() ([]string, error) {
+	paths := append([]string(nil), f.SubmodulePath...)
+	sort.Strings(paths)
+	return paths, nil
+}
@@ -1,11 +1,17 @@
 module MyGo/mcpCodeCheck
 
-go 1.20
+go 1.23.0
 
-require github.com/mark3labs/mcp-go v0.17.0
+require (
+	github.com/fsnotify/fsnotify v1.7.0
+	github.com/mark3labs/mcp-go v0.17.0
+	golang.org/x/tools v0.12.0
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	github.com/google/uuid v1.6.0 // indirect
 	github.com/yosida95/uritemplate/v3 v3.0.2 // indirect
+	golang.org/x/mod v0.12.0 // indirect
 	golang.org/x/sys v0.31.0 // indirect
-)
\ No newline at end of file
+)
@@ -0,0 +1,384 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// defaultTestTimeout go test 的默认超时时间
+const defaultTestTimeout = 10 * time.Minute
+
+// CodeTestRequest 定义测试运行请求结构
+type CodeTestRequest struct {
+	Files             []string `json:"files" description:"参考文件列表（可选）。checkOnlyChanges=false 时测试这些文件所在的包"`
+	ProjectPath       string   `json:"projectPath" description:"项目根目录（可选，优先作为检测起点）"`
+	CheckOnlyChanges  bool     `json:"checkOnlyChanges" description:"是否只测试变更文件所在的包（默认true）" default:"true"`
+	IncludeDependents bool     `json:"includeDependents" description:"是否同时测试依赖变更包的其他包（默认false）"`
+	DependentsDepth   int      `json:"dependentsDepth" description:"依赖包展开层级：1为只包含直接导入方（默认），N为传递N层，0为不限层级"`
+	Timeout           string   `json:"timeout" description:"go test 超时时间，如 30s、5m（默认10m）"`
+	TrunkBranchesArg
+}
+
+// TestRunResult 表示测试运行结果
+type TestRunResult struct {
+	Passed     bool                `json:"Passed"`
+	Summary    TestSummary         `json:"Summary"`
+	Packages   []PackageTestResult `json:"Packages"`
+	Tests      []TestCaseResult    `json:"Tests"`
+	Dependents []DependentPackage  `json:"Dependents,omitempty"`
+	Issues     []Issue             `json:"Issues"`
+	Scope      *ScopeInfo          `json:"Scope,omitempty"`
+}
+
+// TestSummary 测试用例统计
+type TestSummary struct {
+	Total   int `json:"Total"`
+	Passed  int `json:"Passed"`
+	Failed  int `json:"Failed"`
+	Skipped int `json:"Skipped"`
+}
+
+// PackageTestResult 单个包的测试结果
+type PackageTestResult struct {
+	Package     string  `json:"Package"`
+	ProjectRoot string  `json:"ProjectRoot"`
+	Status      string  `json:"Status"`
+	Elapsed     float64 `json:"Elapsed"`
+	Output      string  `json:"Output,omitempty"`
+}
+
+// TestCaseResult 单个测试用例的结果，仅失败的用例携带输出
+type TestCaseResult struct {
+	Package string  `json:"Package"`
+	Test    string  `json:"Test"`
+	Status  string  `json:"Status"`
+	Elapsed float64 `json:"Elapsed"`
+	Output  string  `json:"Output,omitempty"`
+}
+
+// testEvent go test -json 输出的事件（参见 go doc test2json）
+type testEvent struct {
+	Action      string  `json:"Action"`
+	Package     string  `json:"Package"`
+	Test        string  `json:"Test"`
+	Elapsed     float64 `json:"Elapsed"`
+	Output      string  `json:"Output"`
+	ImportPath  string  `json:"ImportPath"`  // Go 1.24+ build-output 事件
+	FailedBuild string  `json:"FailedBuild"` // Go 1.24+ 编译失败的包
+}
+
+// testFailurePosRegex 从测试输出中提取失败位置，如 "    foo_test.go:12: ..."
+var testFailurePosRegex = regexp.MustCompile(`^\s+([^\s:]+_test\.go):(\d+):`)
+
+// runGoTest 在项目根目录执行 go test -json 并按包和用例汇总结果
+func runGoTest(ctx context.Context, projectRoot string, packages []string, vendorMode bool, timeout time.Duration) ([]PackageTestResult, []TestCaseResult, error) {
+	args := []string{"test", "-json", "-timeout", timeout.String()}
+	if vendorMode {
+		args = append(args, "-mod=vendor")
+	}
+	args = append(args, packages...)
+	log.Printf("执行命令: go %v", args)
+	log.Printf("命令执行目录: %s", projectRoot)
+
+	// 在 go test 自身超时之外预留一点时间收尾
+	runCtx, cancel := context.WithTimeout(ctx, timeout+30*time.Second)
+	defer cancel()
+
+	cmd := exec.CommandContext(runCtx, "go", args...)
+	cmd.Dir = projectRoot
+	cmd.Env = os.Environ()
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, cmdErr := cmd.Output()
+	log.Printf("命令输出长度: %d，执行错误: %v", len(output), cmdErr)
+
+	if runCtx.Err() == context.DeadlineExceeded {
+		return nil, nil, fmt.Errorf("go test 超时（%s）", timeout)
+	}
+	if cmdErr != nil && len(output) == 0 {
+		return nil, nil, fmt.Errorf("go test 执行失败: %v\n%s", cmdErr, strings.TrimSpace(stderr.String()))
+	}
+
+	type testKey struct{ pkg, test string }
+	pkgResults := make(map[string]*PackageTestResult)
+	var pkgOrder []string
+	testResults := make(map[testKey]*TestCaseResult)
+	var testOrder []testKey
+	outputs := make(map[testKey]*strings.Builder)
+	buildOutputs := make(map[string]*strings.Builder)
+	failedBuilds := make(map[string]string)
+
+	dec := json.NewDecoder(bytes.NewReader(output))
+	for {
+		var ev testEvent
+		if err := dec.Decode(&ev); err != nil {
+			if err != io.EOF {
+				log.Printf("go test 输出解析中断: %v", err)
+			}
+			break
+		}
+		if ev.Action == "build-output" {
+			if buildOutputs[ev.ImportPath] == nil {
+				buildOutputs[ev.ImportPath] = &strings.Builder{}
+			}
+			buildOutputs[ev.ImportPath].WriteString(ev.Output)
+			continue
+		}
+		if ev.Package == "" {
+			continue
+		}
+		if ev.FailedBuild != "" {
+			failedBuilds[ev.Package] = ev.FailedBuild
+		}
+
+		key := testKey{ev.Package, ev.Test}
+		if ev.Action == "output" {
+			if outputs[key] == nil {
+				outputs[key] = &strings.Builder{}
+			}
+			outputs[key].WriteString(ev.Output)
+			continue
+		}
+		if ev.Action != "pass" && ev.Action != "fail" && ev.Action != "skip" {
+			continue
+		}
+
+		if ev.Test == "" {
+			if _, ok := pkgResults[ev.Package]; !ok {
+				pkgOrder = append(pkgOrder, ev.Package)
+			}
+			pkgResults[ev.Package] = &PackageTestResult{Package: ev.Package, ProjectRoot: projectRoot, Status: ev.Action, Elapsed: ev.Elapsed}
+			continue
+		}
+		if _, ok := testResults[key]; !ok {
+			testOrder = append(testOrder, key)
+		}
+		testResults[key] = &TestCaseResult{Package: ev.Package, Test: ev.Test, Status: ev.Action, Elapsed: ev.Elapsed}
+	}
+
+	packagesOut := make([]PackageTestResult, 0, len(pkgOrder))
+	for _, pkg := range pkgOrder {
+		res := pkgResults[pkg]
+		if res.Status == "fail" {
+			if out := buildOutputs[failedBuilds[pkg]]; out != nil {
+				res.Output = out.String()
+			}
+			if out := outputs[testKey{pkg, ""}]; out != nil {
+				res.Output += out.String()
+			}
+		}
+		packagesOut = append(packagesOut, *res)
+	}
+
+	// 编译失败时错误信息只出现在 stderr 中，补充到失败的包上
+	if stderrText := strings.TrimSpace(stderr.String()); stderrText != "" && cmdErr != nil {
+		attached := false
+		for i := range packagesOut {
+			if packagesOut[i].Status == "fail" && !strings.Contains(packagesOut[i].Output, "--- FAIL") {
+				packagesOut[i].Output += stderrText + "\n"
+				attached = true
+				break
+			}
+		}
+		if !attached && len(packagesOut) == 0 {
+			return nil, nil, fmt.Errorf("go test 执行失败: %v\n%s", cmdErr, stderrText)
+		}
+	}
+
+	testsOut := make([]TestCaseResult, 0, len(testOrder))
+	for _, key := range testOrder {
+		res := testResults[key]
+		if res.Status == "fail" {
+			if out := outputs[key]; out != nil {
+				res.Output = out.String()
+			}
+		}
+		testsOut = append(testsOut, *res)
+	}
+	return packagesOut, testsOut, nil
+}
+
+// testFailureIssue 将失败的测试用例转换为 Issue，尽量定位到失败断言所在行
+func testFailureIssue(projectRoot, packageDir string, tc TestCaseResult) Issue {
+	pos := Pos{Filename: packageDir}
+	for _, line := range strings.Split(tc.Output, "\n") {
+		if m := testFailurePosRegex.FindStringSubmatch(line); m != nil {
+			lineNo, _ := strconv.Atoi(m[2])
+			pos = Pos{Filename: filepath.Join(packageDir, m[1]), Line: lineNo, Column: 1}
+			break
+		}
+	}
+	if pos.Filename == "" {
+		pos.Filename = projectRoot
+	}
+	return Issue{
+		FromLinter: "go test",
+		Text:       fmt.Sprintf("测试失败: %s.%s\n%s", tc.Package, tc.Test, strings.TrimSpace(tc.Output)),
+		Severity:   "error",
+		Pos:        pos,
+	}
+}
+
+// handleCodeTestRequest 处理测试运行请求
+func handleCodeTestRequest(ctx context.Context, req mcp.CallToolRequest) (result *mcp.CallToolResult, err error) {
+	// 捕获任何 panic 并转换为错误返回
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("发生 panic: %v", r)
+			result = buildErrorResult(fmt.Sprintf("内部错误: %v", r))
+			err = nil
+		}
+	}()
+
+	log.Printf("收到测试运行请求: name=%s args=%v", req.Params.Name, req.Params.Arguments)
+
+	var testReq CodeTestRequest
+	if req.Params.Arguments == nil {
+		req.Params.Arguments = map[string]interface{}{}
+	}
+	cfg := applyToolDefaults("code_test", req.Params.Arguments)
+	if !cfg.backendEnabled(backendGoTest) {
+		return backendDisabledResult(backendGoTest, cfg), nil
+	}
+	argsBytes, _ := json.Marshal(req.Params.Arguments)
+	if err := json.Unmarshal(argsBytes, &testReq); err != nil {
+		return buildErrorResult(fmt.Sprintf("无效的请求参数: %v", err)), nil
+	}
+	if _, exists := req.Params.Arguments["checkOnlyChanges"]; !exists {
+		testReq.CheckOnlyChanges = true
+	}
+	if _, exists := req.Params.Arguments["dependentsDepth"]; !exists {
+		testReq.DependentsDepth = 1
+	}
+
+	timeout := defaultTestTimeout
+	if testReq.Timeout != "" {
+		d, err := time.ParseDuration(testReq.Timeout)
+		if err != nil || d <= 0 {
+			return buildErrorResult(fmt.Sprintf("无效的 timeout: %s", testReq.Timeout)), nil
+		}
+		timeout = d
+	}
+
+	baseDir, err := resolveBaseDir(testReq.ProjectPath, testReq.Files)
+	if err != nil {
+		return buildPathErrorResult(err), nil
+	}
+	log.Printf("测试起点目录: %s", baseDir)
+
+	// 确定受影响的包：变更文件所在包，或 files 所在包，或整个模块
+	projectPackages, scope, err := resolveScopePackages(baseDir, testReq.ProjectPath, testReq.Files, testReq.CheckOnlyChanges, ScopeOptions{TrunkBranches: testReq.TrunkBranches, Config: cfg})
+	if err != nil {
+		return buildErrorResult(err.Error()), nil
+	}
+
+	testResult := &TestRunResult{
+		Passed:   true,
+		Packages: make([]PackageTestResult, 0),
+		Tests:    make([]TestCaseResult, 0),
+		Issues:   make([]Issue, 0),
+		Scope:    scope,
+	}
+
+	projectRoots := make([]string, 0, len(projectPackages))
+	for projectRoot := range projectPackages {
+		projectRoots = append(projectRoots, projectRoot)
+	}
+	sort.Strings(projectRoots)
+
+	for _, projectRoot := range projectRoots {
+		packages := projectPackages[projectRoot]
+		sort.Strings(packages)
+		vendorMode := autoDetectVendorMode(projectRoot)
+
+		if testReq.IncludeDependents {
+			dependents, err := expandDependents(ctx, projectRoot, packages, testReq.DependentsDepth, vendorMode)
+			if err != nil {
+				log.Printf("查找依赖包失败（项目: %s）: %v", projectRoot, err)
+			} else {
+				for _, dep := range dependents {
+					packages = append(packages, dep.Package)
+				}
+				testResult.Dependents = append(testResult.Dependents, dependents...)
+			}
+		}
+
+		log.Printf("测试项目 %s 的包: %v", projectRoot, packages)
+		pkgResults, testCases, err := runGoTest(ctx, projectRoot, packages, vendorMode, timeout)
+		if err != nil {
+			testResult.Passed = false
+			testResult.Issues = append(testResult.Issues, Issue{
+				FromLinter: "go test",
+				Text:       fmt.Sprintf("项目 %s 测试执行失败: %v", projectRoot, err),
+				Severity:   "error",
+				Pos:        Pos{Filename: "system"},
+			})
+			continue
+		}
+
+		// 导入路径 -> 包目录，用于定位失败位置
+		pkgDirs := make(map[string]string)
+		if pkgs, err := listModulePackages(ctx, projectRoot, vendorMode); err == nil {
+			for _, pkg := range pkgs {
+				pkgDirs[pkg.ImportPath] = pkg.Dir
+			}
+		}
+
+		for _, pr := range pkgResults {
+			if pr.Status == "fail" {
+				testResult.Passed = false
+				hasFailedTest := false
+				for _, tc := range testCases {
+					if tc.Package == pr.Package && tc.Status == "fail" {
+						hasFailedTest = true
+						break
+					}
+				}
+				// 没有失败用例的包级失败（编译失败、init panic、超时等）单独报告
+				if !hasFailedTest {
+					dir := pkgDirs[pr.Package]
+					if dir == "" {
+						dir = projectRoot
+					}
+					testResult.Issues = append(testResult.Issues, Issue{
+						FromLinter: "go test",
+						Text:       fmt.Sprintf("包测试失败: %s\n%s", pr.Package, strings.TrimSpace(pr.Output)),
+						Severity:   "error",
+						Pos:        Pos{Filename: dir},
+					})
+				}
+			}
+		}
+		for _, tc := range testCases {
+			testResult.Summary.Total++
+			switch tc.Status {
+			case "pass":
+				testResult.Summary.Passed++
+			case "skip":
+				testResult.Summary.Skipped++
+			case "fail":
+				testResult.Summary.Failed++
+				testResult.Passed = false
+				testResult.Issues = append(testResult.Issues, testFailureIssue(projectRoot, pkgDirs[tc.Package], tc))
+			}
+		}
+		testResult.Packages = append(testResult.Packages, pkgResults...)
+		testResult.Tests = append(testResult.Tests, testCases...)
+	}
+
+	return buildToolResult(testResult, cfg), nil
+}
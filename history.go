@@ -0,0 +1,368 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// cacheDirEnv 缓存目录（默认为用户缓存目录下的 lint-mcp）
+const cacheDirEnv = "LINT_MCP_CACHE_DIR"
+
+const (
+	// maxHistoryRuns 每个项目保留的检查记录数
+	maxHistoryRuns = 50
+	// defaultHistoryLimit lint_history 默认返回的记录数
+	defaultHistoryLimit = 10
+)
+
+// LintRunOptions 影响检查范围的请求参数，参数相同的两次检查才互相比较
+type LintRunOptions struct {
+	CheckOnlyChanges  bool     `json:"CheckOnlyChanges"`
+	Files             []string `json:"Files,omitempty"`
+	Tests             string   `json:"Tests"`
+	BuildTags         []string `json:"BuildTags,omitempty"`
+	Platforms         []string `json:"Platforms,omitempty"`
+	IncludeDependents bool     `json:"IncludeDependents,omitempty"`
+	DependentsDepth   int      `json:"DependentsDepth,omitempty"`
+	IncludeGenerated  bool     `json:"IncludeGenerated,omitempty"`
+}
+
+// LintRunDiff 与同一项目上一次相同范围检查的比较结果（按问题指纹）
+type LintRunDiff struct {
+	RunID         string `json:"RunID,omitempty"`
+	PreviousRunID string `json:"PreviousRunID,omitempty"`
+	PreviousTime  string `json:"PreviousTime,omitempty"`
+	// New 本次新出现的问题指纹（对应 Issues 中的问题）
+	New []string `json:"New"`
+	// Fixed 上次存在、本次已消失的问题
+	Fixed []Issue `json:"Fixed"`
+	// Unchanged 两次都存在的问题指纹
+	Unchanged []string `json:"Unchanged"`
+}
+
+// lintRun 一次检查记录
+type lintRun struct {
+	ID       string         `json:"ID"`
+	Time     time.Time      `json:"Time"`
+	ScopeKey string         `json:"ScopeKey"`
+	Options  LintRunOptions `json:"Options"`
+	Issues   []Issue        `json:"Issues"`
+	// 与上一次相同范围检查比较的数量，没有可比较的记录时 HasBaseline 为 false
+	HasBaseline bool `json:"HasBaseline"`
+	New         int  `json:"New"`
+	Fixed       int  `json:"Fixed"`
+	Unchanged   int  `json:"Unchanged"`
+}
+
+// runHistory 单个项目的检查记录文件
+type runHistory struct {
+	ProjectRoot string    `json:"ProjectRoot"`
+	Runs        []lintRun `json:"Runs"` // 按时间先后排列
+}
+
+// historyMu 串行化检查记录文件的读写
+var historyMu sync.Mutex
+
+// cacheDir 返回 lint-mcp 的缓存目录
+func cacheDir() (string, error) {
+	if dir := os.Getenv(cacheDirEnv); dir != "" {
+		return dir, nil
+	}
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("无法确定缓存目录（可设置环境变量 %s）: %v", cacheDirEnv, err)
+	}
+	return filepath.Join(base, "lint-mcp"), nil
+}
+
+// historyFile 返回项目检查记录文件的路径（按项目路径哈希命名）
+func historyFile(projectRoot string) (string, error) {
+	dir, err := cacheDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(filepath.Clean(projectRoot)))
+	return filepath.Join(dir, "history", hex.EncodeToString(sum[:8])+".json"), nil
+}
+
+// loadRunHistory 读取项目的检查记录，文件不存在时返回空记录
+func loadRunHistory(projectRoot string) (*runHistory, string, error) {
+	path, err := historyFile(projectRoot)
+	if err != nil {
+		return nil, "", err
+	}
+	h := &runHistory{ProjectRoot: projectRoot}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return h, path, nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if err := json.Unmarshal(data, h); err != nil {
+		log.Printf("检查记录文件 %s 已损坏，重新开始记录: %v", path, err)
+		return &runHistory{ProjectRoot: projectRoot}, path, nil
+	}
+	return h, path, nil
+}
+
+// save 写入检查记录（先写临时文件再改名，避免中断时留下不完整的文件）
+func (h *runHistory) save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// lintRunOptions 提取请求中影响检查范围的参数
+func lintRunOptions(lintReq CodeLintRequest, tests string) LintRunOptions {
+	opts := LintRunOptions{
+		CheckOnlyChanges:  lintReq.CheckOnlyChanges,
+		Tests:             tests,
+		BuildTags:         lintReq.BuildTags,
+		Platforms:         lintReq.Platforms,
+		IncludeDependents: lintReq.IncludeDependents,
+		IncludeGenerated:  lintReq.IncludeGenerated,
+	}
+	if lintReq.IncludeDependents {
+		opts.DependentsDepth = lintReq.DependentsDepth
+	}
+	// checkOnlyChanges=true 时 files 只用于确定起点，不影响检查范围
+	if !lintReq.CheckOnlyChanges {
+		opts.Files = append([]string(nil), lintReq.Files...)
+		sort.Strings(opts.Files)
+	}
+	return opts
+}
+
+// recordLintRun 保存本次检查结果，并与同一项目上一次相同范围的检查比较，结果写入 result.Diff。
+// 记录失败不影响检查结果
+func recordLintRun(projectRoot string, lintReq CodeLintRequest, tests string, result *LintResult) {
+	if !result.Partial {
+		for _, issue := range result.Issues {
+			if isToolFailure(issue) {
+				result.Partial = true
+				break
+			}
+		}
+	}
+	if result.Partial {
+		// 问题不完整：与上次比较会把未检查到的问题都算作已修复，保存后还会成为错误的基准
+		log.Printf("golangci-lint 未执行或执行失败，不记录本次检查（项目: %s）", projectRoot)
+		return
+	}
+
+	opts := lintRunOptions(lintReq, tests)
+	keyData, _ := json.Marshal(opts)
+	scopeKey := string(keyData)
+
+	historyMu.Lock()
+	defer historyMu.Unlock()
+
+	h, path, err := loadRunHistory(projectRoot)
+	if err != nil {
+		log.Printf("读取检查记录失败（项目: %s）: %v", projectRoot, err)
+		return
+	}
+
+	now := time.Now()
+	run := lintRun{
+		ID:       fmt.Sprintf("%s-%04d", now.Format("20060102T150405"), now.Nanosecond()/100000),
+		Time:     now,
+		ScopeKey: scopeKey,
+		Options:  opts,
+		Issues:   result.Issues,
+	}
+
+	diff := &LintRunDiff{RunID: run.ID, New: []string{}, Fixed: []Issue{}, Unchanged: []string{}}
+	var previous *lintRun
+	for i := len(h.Runs) - 1; i >= 0; i-- {
+		if h.Runs[i].ScopeKey == scopeKey {
+			previous = &h.Runs[i]
+			break
+		}
+	}
+	if previous != nil {
+		diff.PreviousRunID = previous.ID
+		diff.PreviousTime = previous.Time.Format(time.RFC3339)
+		diff.New, diff.Fixed, diff.Unchanged = diffIssues(previous.Issues, result.Issues)
+		run.HasBaseline = true
+		run.New, run.Fixed, run.Unchanged = len(diff.New), len(diff.Fixed), len(diff.Unchanged)
+		log.Printf("与上次检查 %s 相比：新增 %d，已修复 %d，未变化 %d", previous.ID, run.New, run.Fixed, run.Unchanged)
+	} else {
+		// 没有可比较的记录时所有问题都视为新问题
+		for _, issue := range result.Issues {
+			diff.New = append(diff.New, issue.Fingerprint)
+		}
+	}
+	result.Diff = diff
+
+	h.ProjectRoot = projectRoot
+	h.Runs = append(h.Runs, run)
+	if len(h.Runs) > maxHistoryRuns {
+		h.Runs = h.Runs[len(h.Runs)-maxHistoryRuns:]
+	}
+	if err := h.save(path); err != nil {
+		log.Printf("保存检查记录失败（项目: %s）: %v", projectRoot, err)
+	}
+}
+
+// diffIssues 按指纹比较两次检查的问题。同一指纹可能对应多个问题（相同代码行），按数量比较：
+// 数量增加的部分为新增，减少的部分为已修复
+func diffIssues(previous, current []Issue) ([]string, []Issue, []string) {
+	prevByFingerprint := make(map[string][]Issue)
+	for _, issue := range previous {
+		prevByFingerprint[issue.Fingerprint] = append(prevByFingerprint[issue.Fingerprint], issue)
+	}
+	curCount := make(map[string]int)
+	for _, issue := range current {
+		curCount[issue.Fingerprint]++
+	}
+
+	newFingerprints, unchanged := []string{}, []string{}
+	used := make(map[string]int)
+	for _, issue := range current {
+		fp := issue.Fingerprint
+		if used[fp] < len(prevByFingerprint[fp]) {
+			unchanged = append(unchanged, fp)
+		} else {
+			newFingerprints = append(newFingerprints, fp)
+		}
+		used[fp]++
+	}
+
+	fixed := []Issue{}
+	for fp, issues := range prevByFingerprint {
+		if extra := len(issues) - curCount[fp]; extra > 0 {
+			fixed = append(fixed, issues[len(issues)-extra:]...)
+		}
+	}
+	sort.Slice(fixed, func(i, j int) bool {
+		if fixed[i].Pos.Filename != fixed[j].Pos.Filename {
+			return fixed[i].Pos.Filename < fixed[j].Pos.Filename
+		}
+		return fixed[i].Pos.Line < fixed[j].Pos.Line
+	})
+	return newFingerprints, fixed, unchanged
+}
+
+// mergeRunDiffs 合并多个检查目标的比较结果（RunID 各不相同，合并后省略）
+func mergeRunDiffs(merged, diff *LintRunDiff) *LintRunDiff {
+	if diff == nil {
+		return merged
+	}
+	if merged == nil {
+		merged = &LintRunDiff{New: []string{}, Fixed: []Issue{}, Unchanged: []string{}}
+	}
+	merged.New = append(merged.New, diff.New...)
+	merged.Fixed = append(merged.Fixed, diff.Fixed...)
+	merged.Unchanged = append(merged.Unchanged, diff.Unchanged...)
+	return merged
+}
+
+// LintHistoryRequest lint_history 工具参数
+type LintHistoryRequest struct {
+	ProjectPath string   `json:"projectPath" description:"项目根目录（与 code_lint 的检测起点一致）"`
+	Files       []string `json:"files" description:"参考文件列表（可选，用于确定项目位置）"`
+	Limit       int      `json:"limit" description:"返回的记录数（默认10）"`
+}
+
+// LintHistoryResult lint_history 工具结果
+type LintHistoryResult struct {
+	ProjectRoot string           `json:"ProjectRoot"`
+	Runs        []LintRunSummary `json:"Runs"` // 最近的在前
+}
+
+// LintRunSummary 单次检查的概要
+type LintRunSummary struct {
+	ID          string         `json:"ID"`
+	Time        string         `json:"Time"`
+	Options     LintRunOptions `json:"Options"`
+	Issues      int            `json:"Issues"`
+	ByLinter    map[string]int `json:"ByLinter,omitempty"`
+	HasBaseline bool           `json:"HasBaseline"` // 是否有上一次相同范围的检查可比较
+	New         int            `json:"New"`
+	Fixed       int            `json:"Fixed"`
+	Unchanged   int            `json:"Unchanged"`
+}
+
+// handleLintHistoryRequest 处理检查历史查询请求
+func handleLintHistoryRequest(ctx context.Context, req mcp.CallToolRequest) (result *mcp.CallToolResult, err error) {
+	// 捕获任何 panic 并转换为错误返回
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("发生 panic: %v", r)
+			result = buildErrorResult(fmt.Sprintf("内部错误: %v", r))
+			err = nil
+		}
+	}()
+
+	log.Printf("收到检查历史请求: name=%s args=%v", req.Params.Name, req.Params.Arguments)
+
+	var historyReq LintHistoryRequest
+	if req.Params.Arguments == nil {
+		req.Params.Arguments = map[string]interface{}{}
+	}
+	cfg := applyToolDefaults("lint_history", req.Params.Arguments)
+	argsBytes, _ := json.Marshal(req.Params.Arguments)
+	if err := json.Unmarshal(argsBytes, &historyReq); err != nil {
+		return buildErrorResult(fmt.Sprintf("无效的请求参数: %v", err)), nil
+	}
+	if historyReq.Limit <= 0 {
+		historyReq.Limit = defaultHistoryLimit
+	}
+
+	baseDir, err := resolveBaseDir(historyReq.ProjectPath, historyReq.Files)
+	if err != nil {
+		return buildPathErrorResult(err), nil
+	}
+
+	historyMu.Lock()
+	h, _, err := loadRunHistory(baseDir)
+	historyMu.Unlock()
+	if err != nil {
+		return buildErrorResult(fmt.Sprintf("读取检查记录失败: %v", err)), nil
+	}
+
+	history := &LintHistoryResult{ProjectRoot: baseDir, Runs: []LintRunSummary{}}
+	for i := len(h.Runs) - 1; i >= 0 && len(history.Runs) < historyReq.Limit; i-- {
+		run := h.Runs[i]
+		summary := LintRunSummary{
+			ID:          run.ID,
+			Time:        run.Time.Format(time.RFC3339),
+			Options:     run.Options,
+			Issues:      len(run.Issues),
+			HasBaseline: run.HasBaseline,
+			New:         run.New,
+			Fixed:       run.Fixed,
+			Unchanged:   run.Unchanged,
+		}
+		if len(run.Issues) > 0 {
+			summary.ByLinter = make(map[string]int)
+			for _, issue := range run.Issues {
+				summary.ByLinter[issue.FromLinter]++
+			}
+		}
+		history.Runs = append(history.Runs, summary)
+	}
+	return buildToolResult(history, outputConfig(ctx, cfg)), nil
+}
@@ -29,8 +29,8 @@ function startMCPServer() {
     process.exit(1);
   }
   
-  // 启动子进程
-  const child = spawn(binaryPath, [], {
+  // 启动子进程，透传命令行参数（如 -allow-root）
+  const child = spawn(binaryPath, process.argv.slice(2), {
     stdio: 'inherit',
     env: process.env
   });
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxParallelLintEnv 同时运行的 golangci-lint 进程数上限（跨所有 MCP 请求），默认 1
+const maxParallelLintEnv = "LINT_MCP_MAX_PARALLEL_LINT"
+
+// ResourceLimits golangci-lint 子进程的资源限制（.lint-mcp.yaml 的 limits 配置项），未设置的项不做限制
+type ResourceLimits struct {
+	// Concurrency golangci-lint --concurrency，0 表示使用 golangci-lint 默认值（CPU 数）
+	Concurrency int `yaml:"concurrency" json:"concurrency,omitempty"`
+	// GOMAXPROCS 子进程（含其调用的 go list）可使用的 CPU 数
+	GOMAXPROCS int `yaml:"gomaxprocs" json:"gomaxprocs,omitempty"`
+	// GOMEMLIMIT Go 运行时的软内存上限，如 "2GiB"；未设置但配置了 memoryLimit 时取其 90%
+	GOMEMLIMIT string `yaml:"gomemlimit" json:"gomemlimit,omitempty"`
+	// GOGC 垃圾回收目标百分比，如 "50" 或 "off"
+	GOGC string `yaml:"gogc" json:"gogc,omitempty"`
+	// MemoryLimit 内存硬上限，如 "4GiB"；仅 Linux 强制执行：优先 cgroup（systemd-run --user --scope），否则 rlimit（ulimit -v）
+	MemoryLimit string `yaml:"memoryLimit" json:"memoryLimit,omitempty"`
+}
+
+// sizeRegex 内存大小，如 "512MiB"、"4G"、"1073741824"
+var sizeRegex = regexp.MustCompile(`(?i)^(\d+)\s*(b|k|kb|kib|m|mb|mib|g|gb|gib|t|tb|tib)?$`)
+
+// parseByteSize 解析内存大小，单位均按 1024 进制
+func parseByteSize(s string) (int64, error) {
+	m := sizeRegex.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return 0, fmt.Errorf("无效的内存大小 %q（示例: 512MiB、4GiB）", s)
+	}
+	n, err := strconv.ParseInt(m[1], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("无效的内存大小 %q: %v", s, err)
+	}
+	switch unit := strings.ToLower(m[2]); {
+	case strings.HasPrefix(unit, "k"):
+		n <<= 10
+	case strings.HasPrefix(unit, "m"):
+		n <<= 20
+	case strings.HasPrefix(unit, "g"):
+		n <<= 30
+	case strings.HasPrefix(unit, "t"):
+		n <<= 40
+	}
+	return n, nil
+}
+
+// validate 检查资源限制取值
+func (l *ResourceLimits) validate() error {
+	if l == nil {
+		return nil
+	}
+	if l.Concurrency < 0 || l.GOMAXPROCS < 0 {
+		return fmt.Errorf("limits.concurrency 与 limits.gomaxprocs 不能为负数")
+	}
+	for name, value := range map[string]string{"gomemlimit": l.GOMEMLIMIT, "memoryLimit": l.MemoryLimit} {
+		if value == "" {
+			continue
+		}
+		if _, err := parseByteSize(value); err != nil {
+			return fmt.Errorf("limits.%s: %v", name, err)
+		}
+	}
+	if l.GOGC != "" && l.GOGC != "off" {
+		if _, err := strconv.Atoi(l.GOGC); err != nil {
+			return fmt.Errorf("limits.gogc 只支持整数或 off，实际为 %q", l.GOGC)
+		}
+	}
+	return nil
+}
+
+// merge 按字段覆盖，返回被覆盖的字段名
+func (l *ResourceLimits) merge(other *ResourceLimits) []string {
+	var fields []string
+	if other.Concurrency != 0 {
+		l.Concurrency = other.Concurrency
+		fields = append(fields, "concurrency")
+	}
+	if other.GOMAXPROCS != 0 {
+		l.GOMAXPROCS = other.GOMAXPROCS
+		fields = append(fields, "gomaxprocs")
+	}
+	if other.GOMEMLIMIT != "" {
+		l.GOMEMLIMIT = other.GOMEMLIMIT
+		fields = append(fields, "gomemlimit")
+	}
+	if other.GOGC != "" {
+		l.GOGC = other.GOGC
+		fields = append(fields, "gogc")
+	}
+	if other.MemoryLimit != "" {
+		l.MemoryLimit = other.MemoryLimit
+		fields = append(fields, "memoryLimit")
+	}
+	return fields
+}
+
+// golangciArgs 为 golangci-lint run 补充 --concurrency（调用方已指定时不覆盖）
+func (l *ResourceLimits) golangciArgs(args []string) []string {
+	if l == nil || l.Concurrency == 0 || len(args) == 0 || args[0] != "run" {
+		return args
+	}
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-j") || strings.HasPrefix(arg, "--concurrency") {
+			return args
+		}
+	}
+	limited := append([]string{"run", fmt.Sprintf("--concurrency=%d", l.Concurrency)}, args[1:]...)
+	return limited
+}
+
+// env 返回 Go 运行时调优相关的环境变量
+func (l *ResourceLimits) env() []string {
+	if l == nil {
+		return nil
+	}
+	var env []string
+	if l.GOMAXPROCS > 0 {
+		env = append(env, fmt.Sprintf("GOMAXPROCS=%d", l.GOMAXPROCS))
+	}
+	if l.GOMEMLIMIT != "" {
+		if n, err := parseByteSize(l.GOMEMLIMIT); err == nil {
+			env = append(env, fmt.Sprintf("GOMEMLIMIT=%d", n))
+		}
+	} else if l.MemoryLimit != "" {
+		// 在硬上限之前让 GC 更积极地回收，减少被强制终止的概率
+		if n, err := parseByteSize(l.MemoryLimit); err == nil {
+			env = append(env, fmt.Sprintf("GOMEMLIMIT=%d", n/10*9))
+		}
+	}
+	if l.GOGC != "" {
+		env = append(env, "GOGC="+l.GOGC)
+	}
+	return env
+}
+
+var (
+	systemdScopeOnce sync.Once
+	systemdScopeOK   bool
+)
+
+// systemdScopeAvailable 判断能否通过 systemd-run --user --scope 创建带内存上限的 cgroup（只探测一次）
+func systemdScopeAvailable() bool {
+	systemdScopeOnce.Do(func() {
+		if _, err := exec.LookPath("systemd-run"); err != nil {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		err := exec.CommandContext(ctx, "systemd-run", "--user", "--scope", "--quiet", "-p", "MemoryMax=1G", "true").Run()
+		systemdScopeOK = err == nil
+		if err != nil {
+			log.Printf("systemd-run --user --scope 不可用（%v），内存上限改用 rlimit", err)
+		}
+	})
+	return systemdScopeOK
+}
+
+// wrapMemoryLimit 按 memoryLimit 包装命令：cgroup 限制整个进程树的实际内存，
+// rlimit 限制每个进程的虚拟内存（对子进程同样生效）
+func (l *ResourceLimits) wrapMemoryLimit(name string, args []string) (string, []string) {
+	if l == nil || l.MemoryLimit == "" {
+		return name, args
+	}
+	limit, err := parseByteSize(l.MemoryLimit)
+	if err != nil {
+		return name, args
+	}
+	if runtime.GOOS != "linux" {
+		log.Printf("limits.memoryLimit 仅在 Linux 上强制执行，当前系统 %s 只设置 GOMEMLIMIT", runtime.GOOS)
+		return name, args
+	}
+	if systemdScopeAvailable() {
+		wrapped := []string{"--user", "--scope", "--quiet", "--collect", "-p", fmt.Sprintf("MemoryMax=%d", limit), "-p", "MemorySwapMax=0", "--", name}
+		return "systemd-run", append(wrapped, args...)
+	}
+	wrapped := []string{"-c", fmt.Sprintf(`ulimit -v %d && exec "$0" "$@"`, limit/1024), name}
+	return "sh", append(wrapped, args...)
+}
+
+// resourceLimitError golangci-lint 因资源上限被终止
+type resourceLimitError struct {
+	msg string
+}
+
+func (e *resourceLimitError) Error() string { return e.msg }
+
+// limitFailure 判断进程是否因资源上限被终止，返回说明
+func (l *ResourceLimits) limitFailure(err error, output []byte) string {
+	if l == nil || l.MemoryLimit == "" || err == nil {
+		return ""
+	}
+	killed := false
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && strings.Contains(exitErr.String(), "signal: killed") {
+		killed = true
+	}
+	text := string(output)
+	if strings.Contains(text, "out of memory") || strings.Contains(text, "cannot allocate memory") {
+		killed = true
+	}
+	if !killed {
+		return ""
+	}
+	return fmt.Sprintf("golangci-lint 超出内存上限 %s（limits.memoryLimit）被终止，请缩小检查范围、降低 limits.concurrency 或提高上限", l.MemoryLimit)
+}
+
+// lintSlots 全局并发槽位，限制所有请求同时运行的 golangci-lint 进程数
+var lintSlots = make(chan struct{}, 1)
+
+// initLintSlots 按命令行参数 -max-parallel-lint 或环境变量 LINT_MCP_MAX_PARALLEL_LINT 设置并发上限（命令行优先）
+func initLintSlots(flagValue int) error {
+	n := flagValue
+	if n == 0 {
+		if env := os.Getenv(maxParallelLintEnv); env != "" {
+			v, err := strconv.Atoi(env)
+			if err != nil {
+				return fmt.Errorf("环境变量 %s 必须是正整数: %q", maxParallelLintEnv, env)
+			}
+			n = v
+		}
+	}
+	if n == 0 {
+		n = 1
+	}
+	if n < 0 {
+		return fmt.Errorf("golangci-lint 并发上限必须是正整数: %d", n)
+	}
+	lintSlots = make(chan struct{}, n)
+	log.Printf("golangci-lint 并发上限: %d", n)
+	return nil
+}
+
+// acquireLintSlot 等待空闲槽位，返回释放函数；请求在等待期间被取消时返回错误
+func acquireLintSlot(ctx context.Context) (func(), error) {
+	start := time.Now()
+	slots := lintSlots
+	select {
+	case slots <- struct{}{}:
+	case <-ctx.Done():
+		log.Printf("等待 golangci-lint 并发槽位时请求已取消: %v", ctx.Err())
+		return nil, ctx.Err()
+	}
+	if waited := time.Since(start); waited > time.Second {
+		log.Printf("等待 golangci-lint 并发槽位 %v", waited.Round(time.Millisecond))
+	}
+	return func() { <-slots }, nil
+}
+
+// runGolangciCommand 在全局并发槽位内以资源限制 limits（调用方已解析的生效配置，nil 表示不限制）执行 golangci-lint，返回合并输出。
+// 因资源上限被终止时丢弃不完整的输出并返回说明原因的错误；请求被取消时终止进程并返回取消错误
+func runGolangciCommand(ctx context.Context, projectRoot string, args []string, bc BuildConfig, limits *ResourceLimits) ([]byte, error) {
+	args = limits.golangciArgs(args)
+	name, cmdArgs := limits.wrapMemoryLimit("golangci-lint", args)
+
+	cmd := exec.CommandContext(ctx, name, cmdArgs...)
+	// 取消时子进程（如 systemd-run/ulimit 包装）可能仍持有输出管道，最多再等待 1 秒
+	cmd.WaitDelay = time.Second
+	cmd.Dir = projectRoot
+	cmd.Env = append(bc.Env(), limits.env()...)
+	if name != "golangci-lint" || len(limits.env()) > 0 {
+		log.Printf("资源限制: %s %v，环境变量 %v", name, cmdArgs[:len(cmdArgs)-len(args)], limits.env())
+	}
+
+	release, err := acquireLintSlot(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("请求已取消: %w", err)
+	}
+	defer release()
+	output, err := cmd.CombinedOutput()
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("请求已取消: %w", ctx.Err())
+	}
+	if msg := limits.limitFailure(err, output); msg != "" {
+		log.Printf("%s，原始输出: %s", msg, truncateForLog(string(output)))
+		return nil, &resourceLimitError{msg: msg}
+	}
+	return output, err
+}
+
+// truncateForLog 截断过长的日志内容
+func truncateForLog(s string) string {
+	const maxLen = 500
+	if len(s) <= maxLen {
+		return s
+	}
+	return s[:maxLen] + "..."
+}
@@ -3,6 +3,8 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"os"
@@ -19,33 +21,134 @@ func buildErrorResult(message string) *mcp.CallToolResult {
 	lr := &LintResult{Issues: []Issue{{
 		FromLinter: "lint-mcp",
 		Text:       message,
+		Severity:   "error",
 		Pos:        Pos{Filename: "system", Line: 0, Column: 0},
 	}}}
 	b, _ := json.Marshal(lr)
 	return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Type: "text", Text: string(b)}}}
 }
 
+// buildToolResult 按配置的输出格式（json 或 text）构造工具结果
+func buildToolResult(result interface{}, cfg *EffectiveConfig) *mcp.CallToolResult {
+	resultJSON, _ := json.Marshal(result)
+	text := string(resultJSON)
+	if cfg != nil && cfg.OutputFormat == outputFormatText {
+		text = renderTextResult(resultJSON)
+	}
+	return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Type: "text", Text: text}}}
+}
+
+// renderTextResult 将 JSON 结果渲染为 "文件:行:列: 描述 (linter)" 形式的纯文本
+// 各工具结果共有的 Issues、Passed、Scope 字段参与渲染，其余字段只在 JSON 格式中提供；
+// 没有问题列表的结果（如 lint_history）无法按问题渲染，保持 JSON
+func renderTextResult(resultJSON []byte) string {
+	var common struct {
+		Issues []Issue    `json:"Issues"`
+		Passed *bool      `json:"Passed"`
+		Scope  *ScopeInfo `json:"Scope"`
+	}
+	if err := json.Unmarshal(resultJSON, &common); err != nil || (common.Issues == nil && common.Passed == nil) {
+		return string(resultJSON)
+	}
+
+	var sb strings.Builder
+	if common.Scope != nil && common.Scope.Explanation != "" {
+		fmt.Fprintf(&sb, "检查范围: %s\n", common.Scope.Explanation)
+	}
+	for _, issue := range common.Issues {
+		pos := issue.Pos.Filename
+		if issue.Pos.Line > 0 {
+			pos = fmt.Sprintf("%s:%d", pos, issue.Pos.Line)
+			if issue.Pos.Column > 0 {
+				pos = fmt.Sprintf("%s:%d", pos, issue.Pos.Column)
+			}
+		}
+		if len(issue.Configurations) > 0 {
+			fmt.Fprintf(&sb, "%s: %s (%s) [%s]\n", pos, issue.Text, issue.FromLinter, strings.Join(issue.Configurations, "; "))
+			continue
+		}
+		fmt.Fprintf(&sb, "%s: %s (%s)\n", pos, issue.Text, issue.FromLinter)
+	}
+	if len(common.Issues) == 0 {
+		sb.WriteString("未发现问题\n")
+	} else {
+		fmt.Fprintf(&sb, "共 %d 个问题\n", len(common.Issues))
+	}
+	if common.Passed != nil {
+		if *common.Passed {
+			sb.WriteString("结果: 通过\n")
+		} else {
+			sb.WriteString("结果: 未通过\n")
+		}
+	}
+	return sb.String()
+}
+
+// backendDisabledResult 返回后端被配置禁用的提示
+func backendDisabledResult(backend string, cfg *EffectiveConfig) *mcp.CallToolResult {
+	return buildErrorResult(fmt.Sprintf("后端 %s 已在配置中禁用（backends.%s=false，来源: %s）", backend, backend, cfg.FieldSources["backends."+backend]))
+}
+
 // CodeLintRequest 定义智能代码检查请求结构
 type CodeLintRequest struct {
 	Files            []string `json:"files" description:"参考文件列表（可选，用于确定检查起点）。当checkOnlyChanges=true时，将智能检测当前工作目录的所有变更文件。" required:"false"`
 	ProjectPath      string   `json:"projectPath" description:"项目根目录（可选，优先作为检测起点，建议为Git仓库或包含go.mod的目录）"`
 	CheckOnlyChanges bool     `json:"checkOnlyChanges" description:"是否启用智能变更检测（默认true）。将自动检测Git变更范围：未推送提交、分支分叉点或工作区变更。" default:"true"`
+	// 依赖包展开：把导入了变更包的其他包也纳入检查范围，避免修改导出符号后调用方被破坏却未被发现
+	IncludeDependents bool `json:"includeDependents" description:"是否同时检查依赖变更包的其他包（默认false）"`
+	DependentsDepth   int  `json:"dependentsDepth" description:"依赖包展开层级：1为只包含直接导入方（默认），N为传递N层，0为不限层级"`
+	// 主干分支模式：用于计算分支分叉点，优先于 .lint-mcp.yaml 中的 trunkBranches
+	TrunkBranchesArg
+	// 生成代码默认不检查，按 Go 约定的文件头标记识别
+	IncludeGenerated bool `json:"includeGenerated" description:"是否包含生成代码（带 \"Code generated ... DO NOT EDIT.\" 标记的文件，默认false）"`
+	// 测试代码检查范围：同时驱动文件发现与 golangci-lint 的 --tests 参数
+	Tests string `json:"tests" description:"测试代码检查范围：include（默认，检查生产与测试代码）、exclude（只检查生产代码）、only（只检查测试代码）"`
+	// 构建矩阵：buildTags × platforms 的每个组合各检查一遍（仅类型检查，不执行），结果合并并标注出现的配置
+	BuildTags []string `json:"buildTags" description:"构建标签组合列表（可选），每项为一组逗号分隔的标签，如 [\"\", \"integration\", \"linux,cgo\"]"`
+	Platforms []string `json:"platforms" description:"目标平台列表（可选），形如 [\"linux/amd64\", \"windows/amd64\"]"`
 }
 
 // GolangciLintOutput golangci-lint 的实际输出格式
 type GolangciLintOutput struct {
 	Issues []Issue `json:"Issues"`
 	Report struct {
-		Linters []struct {
-			Name    string `json:"Name"`
-			Enabled bool   `json:"Enabled"`
-		} `json:"Linters"`
+		Linters []LinterState `json:"Linters"`
 	} `json:"Report"`
 }
 
+// LinterState golangci-lint 报告中的单个 linter 及其启用状态
+type LinterState struct {
+	Name        string `json:"Name"`
+	Enabled     bool   `json:"Enabled"`
+	Description string `json:"Description,omitempty"` // 仅 golangci-lint linters 输出提供
+}
+
 // LintResult 表示代码检查结果
 type LintResult struct {
-	Issues []Issue `json:"Issues"`
+	Issues     []Issue            `json:"Issues"`
+	Dependents []DependentPackage `json:"Dependents,omitempty"`
+	Scope      *ScopeInfo         `json:"Scope,omitempty"`
+	// SkippedGenerated 因生成代码而跳过的文件数
+	SkippedGenerated int `json:"SkippedGenerated,omitempty"`
+	// Targets 未指定 projectPath/files 时，按客户端 roots 发现并检查的目标
+	Targets []LintTargetSummary `json:"Targets,omitempty"`
+	// WorkspaceViolation 请求的路径不在允许的工作区内时的详情
+	WorkspaceViolation *WorkspaceViolationError `json:"WorkspaceViolation,omitempty"`
+	// Live 结果来自监听模式的实时问题集时的说明
+	Live *LiveInfo `json:"Live,omitempty"`
+	// Diff 与同一项目上一次相同范围检查的比较（新增/已修复/未变化）
+	Diff *LintRunDiff `json:"Diff,omitempty"`
+	// Passed 不存在达到 severity.failOn 级别的问题；出错时同样为 false
+	Passed bool `json:"Passed"`
+	// Partial golangci-lint 未执行（如编译失败）或执行失败，问题不完整；此时不与上次检查比较，也不记录为新的基准
+	Partial bool `json:"Partial,omitempty"`
+}
+
+// LintTargetSummary 单个检查目标的结果概要
+type LintTargetSummary struct {
+	Path   string     `json:"Path"`
+	Issues int        `json:"Issues"`
+	Scope  *ScopeInfo `json:"Scope,omitempty"`
 }
 
 // Issue 表示单个代码问题
@@ -58,6 +161,13 @@ type Issue struct {
 	Pos                  Pos          `json:"Pos"`
 	ExpectNoLint         bool         `json:"ExpectNoLint"`
 	ExpectedNoLintLinter string       `json:"ExpectedNoLintLinter"`
+	// Configurations 使用 buildTags/platforms 矩阵检查时，出现该问题的构建配置
+	Configurations []string `json:"Configurations,omitempty"`
+	// Fingerprint 问题指纹（linter + 规范化路径 + 描述 + 代码行哈希），不随行号变化，用于跨运行追踪同一问题
+	Fingerprint string `json:"Fingerprint,omitempty"`
+
+	// absPath 问题文件的绝对路径，仅用于去重时区分不同模块中的同名文件，不输出
+	absPath string
 }
 
 type Replacement struct {
@@ -109,6 +219,26 @@ func getProjectRootFromFile(filePath string) (string, error) {
 	return searchDir, nil
 }
 
+// toRelPackagePath 将包目录转换为相对于项目根目录的包路径（如 "./pkg/foo"，根目录为 "."）
+func toRelPackagePath(projectRoot, packageDir string) (string, error) {
+	relPackagePath, err := filepath.Rel(projectRoot, packageDir)
+	if err != nil {
+		return "", err
+	}
+
+	// 在项目根目录的情况下使用 "."
+	if relPackagePath == "" || relPackagePath == "." {
+		return ".", nil
+	}
+	// 确保使用正斜杠（Go模块路径格式）
+	relPackagePath = strings.ReplaceAll(relPackagePath, "\\", "/")
+	// 如果路径不以./开头，添加它
+	if !strings.HasPrefix(relPackagePath, "./") {
+		relPackagePath = "./" + relPackagePath
+	}
+	return relPackagePath, nil
+}
+
 // getPackagesFromFiles 从文件列表中获取包路径列表（去重）
 func getPackagesFromFiles(files []string) (map[string][]string, error) {
 	// 返回 map[projectRoot][]packagePaths 的结构
@@ -142,27 +272,16 @@ func getPackagesFromFiles(files []string) (map[string][]string, error) {
 		}
 
 		// 获取文件所在目录作为包路径
+		// 外部测试包（package xxx_test）与被测包同目录，目录模式会同时加载两者，因此同样归入该目录
 		packageDir := filepath.Dir(file)
 
 		// 转换为相对于项目根目录的包路径
-		relPackagePath, err := filepath.Rel(projectRoot, packageDir)
+		relPackagePath, err := toRelPackagePath(projectRoot, packageDir)
 		if err != nil {
 			log.Printf("警告：无法计算包路径 %s 相对于项目根目录 %s 的路径：%v", packageDir, projectRoot, err)
 			continue
 		}
 
-		// 在项目根目录的情况下使用 "."
-		if relPackagePath == "" || relPackagePath == "." {
-			relPackagePath = "."
-		} else {
-			// 确保使用正斜杠（Go模块路径格式）
-			relPackagePath = strings.ReplaceAll(relPackagePath, "\\", "/")
-			// 如果路径不以./开头，添加它
-			if !strings.HasPrefix(relPackagePath, "./") {
-				relPackagePath = "./" + relPackagePath
-			}
-		}
-
 		// 按项目根目录分组包路径
 		if projectPackages[projectRoot] == nil {
 			projectPackages[projectRoot] = make(map[string]bool)
@@ -190,6 +309,74 @@ func getPackagesFromFiles(files []string) (map[string][]string, error) {
 	return result, nil
 }
 
+// groupFilesByProject 按项目根目录对文件分组，因为变更可能涉及多个项目
+func groupFilesByProject(files []string) map[string][]string {
+	projectFiles := make(map[string][]string)
+	for _, file := range files {
+		projectRoot, err := getProjectRootFromFile(file)
+		if err != nil {
+			log.Printf("警告：无法确定文件 %s 的项目根目录：%v", file, err)
+			continue
+		}
+		projectFiles[projectRoot] = append(projectFiles[projectRoot], file)
+	}
+	return projectFiles
+}
+
+// resolveScopePackages 按检查范围确定各项目需要处理的包：
+// checkOnlyChanges=true 时为变更文件所在的包（同时返回范围说明）；否则为 files 所在的包，或 projectPath 所在的整个模块
+func resolveScopePackages(baseDir, projectPath string, files []string, checkOnlyChanges bool, opts ScopeOptions) (map[string][]string, *ScopeInfo, error) {
+	if checkOnlyChanges {
+		cs, err := getChangeSet(baseDir, opts)
+		if err != nil {
+			return nil, nil, fmt.Errorf("未检测到变更的 Go 文件（起点: %s）: %v", baseDir, err)
+		}
+		projectPackages := make(map[string][]string)
+		if len(cs.Files) > 0 {
+			projectPackages, err = getPackagesFromFiles(cs.Paths())
+			if err != nil {
+				return nil, nil, fmt.Errorf("获取包路径失败: %v", err)
+			}
+		}
+		// 有文件被删除的包也需要重新检查
+		mergeProjectPackages(projectPackages, cs.DeletedPackages())
+		if len(projectPackages) == 0 {
+			return nil, nil, fmt.Errorf("没有找到有效的Go包")
+		}
+		return projectPackages, cs.Scope(), nil
+	}
+
+	if projectPath == "" {
+		projectPackages, err := getPackagesFromFiles(files)
+		if err != nil {
+			return nil, nil, fmt.Errorf("获取包路径失败: %v", err)
+		}
+		return projectPackages, nil, nil
+	}
+
+	root, err := findGoModRoot(baseDir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("起点目录 %s 不在Go模块内: %v", baseDir, err)
+	}
+	return map[string][]string{root: {"./..."}}, nil, nil
+}
+
+// mergeProjectPackages 将 extra 中的包合并进 dst（去重）
+func mergeProjectPackages(dst, extra map[string][]string) {
+	for projectRoot, packages := range extra {
+		existing := make(map[string]bool)
+		for _, pkg := range dst[projectRoot] {
+			existing[pkg] = true
+		}
+		for _, pkg := range packages {
+			if !existing[pkg] {
+				existing[pkg] = true
+				dst[projectRoot] = append(dst[projectRoot], pkg)
+			}
+		}
+	}
+}
+
 // checkGolangciLintInstalled 检查golangci-lint是否已安装
 func checkGolangciLintInstalled() error {
 	_, err := exec.LookPath("golangci-lint")
@@ -233,10 +420,12 @@ func autoDetectVendorMode(projectRoot string) bool {
 }
 
 // findAllGoFiles 在指定目录下查找所有Go文件（备用策略）
-func findAllGoFiles(projectRoot string) ([]string, error) {
+// 按 tests 范围筛选测试文件，跳过 exclude 排除的文件；includeGenerated=false 时同时跳过生成代码，并返回跳过的生成文件数
+func findAllGoFiles(projectRoot string, cfg *EffectiveConfig, includeGenerated bool, tests string) ([]string, int, error) {
 	log.Printf("扫描目录中的所有Go文件: %s", projectRoot)
 
 	var goFiles []string
+	skippedGenerated := 0
 
 	err := filepath.Walk(projectRoot, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
@@ -250,177 +439,114 @@ func findAllGoFiles(projectRoot string) ([]string, error) {
 
 		// 只处理Go文件
 		if !info.IsDir() && strings.HasSuffix(info.Name(), ".go") {
-			// 跳过测试文件和生成的文件
-			if !strings.HasSuffix(info.Name(), "_test.go") &&
-				!strings.Contains(info.Name(), ".pb.go") &&
-				!strings.Contains(info.Name(), ".gen.go") {
-				absPath, err := filepath.Abs(path)
-				if err == nil {
-					goFiles = append(goFiles, absPath)
-					log.Printf("找到Go文件: %s", absPath)
-				}
+			// 跳过测试范围之外的文件、配置排除的文件和生成的文件
+			absPath, err := filepath.Abs(path)
+			if err != nil || !testsModeAccepts(tests, info.Name()) || cfg.isExcluded(absPath) {
+				return nil
+			}
+			if !includeGenerated && isGeneratedGoFile(absPath, cfg) {
+				skippedGenerated++
+				return nil
 			}
+			goFiles = append(goFiles, absPath)
+			log.Printf("找到Go文件: %s", absPath)
 		}
 
 		return nil
 	})
 
 	if err != nil {
-		return nil, fmt.Errorf("扫描目录失败: %v", err)
+		return nil, skippedGenerated, fmt.Errorf("扫描目录失败: %v", err)
 	}
 
 	if len(goFiles) == 0 {
-		return nil, fmt.Errorf("目录中没有找到Go文件")
+		return nil, skippedGenerated, fmt.Errorf("目录中没有找到Go文件")
 	}
 
-	log.Printf("总共扫描到 %d 个Go文件", len(goFiles))
-	return goFiles, nil
+	log.Printf("总共扫描到 %d 个Go文件，跳过 %d 个生成代码文件", len(goFiles), skippedGenerated)
+	return goFiles, skippedGenerated, nil
 }
 
-// detectBaseCommit 智能检测基准提交点
-func detectBaseCommit(projectRoot string) (string, string, error) {
-	log.Printf("智能检测项目 %s 的基准提交点", projectRoot)
+// detectBaseCommit 智能检测基准提交点，返回的 RepoBase 中说明了选中基准的规则
+func detectBaseCommit(repo GitRepo, opts ScopeOptions) RepoBase {
+	log.Printf("智能检测项目 %s 的基准提交点", repo.Dir())
+	base := RepoBase{Root: repo.Dir()}
 
 	// 直接尝试各策略，若命令失败则跳过到下一策略
 
 	// 策略1: 检测未推送的提交
 	log.Printf("策略1: 尝试检测未推送的提交...")
-	cmd := exec.Command("git", "rev-parse", "--abbrev-ref", "HEAD")
-	cmd.Dir = projectRoot
-	branchOutput, err := cmd.Output()
-	if err == nil {
-		currentBranch := strings.TrimSpace(string(branchOutput))
+	currentBranch, err := repo.CurrentBranch()
+	logGitError("获取当前分支", err)
+	if currentBranch != "" {
 		log.Printf("当前分支: %s", currentBranch)
 		remoteBranches := []string{"origin/" + currentBranch, "upstream/" + currentBranch, "remote/" + currentBranch}
 		for _, remoteBranch := range remoteBranches {
-			cmd = exec.Command("git", "rev-parse", "--verify", remoteBranch)
-			cmd.Dir = projectRoot
-			if err := cmd.Run(); err == nil {
-				cmd = exec.Command("git", "rev-list", "--count", remoteBranch+"..HEAD")
-				cmd.Dir = projectRoot
-				countOutput, err := cmd.Output()
-				if err == nil {
-					count := strings.TrimSpace(string(countOutput))
-					if count != "0" {
-						return remoteBranch, fmt.Sprintf("未推送的提交(%s个)", count), nil
-					}
-				}
+			if !repo.RevExists(remoteBranch) {
+				continue
+			}
+			if count, err := repo.CountCommits(remoteBranch, "HEAD"); err == nil && count != 0 {
+				base.BaseCommit = remoteBranch
+				base.Strategy = fmt.Sprintf("未推送的提交(%d个)", count)
+				base.Explanation = fmt.Sprintf("当前分支 %s 领先远程分支 %s %d 个提交，以远程分支为基准只检查未推送的提交", currentBranch, remoteBranch, count)
+				return base
 			}
 		}
 	}
 
-	// 策略2: 检测与主分支的分叉点
-	log.Printf("策略2: 尝试检测与主分支的分叉点...")
-	mainBranch := getActualMainBranch(projectRoot)
-	if mainBranch != "" {
-		cmd = exec.Command("git", "merge-base", "HEAD", mainBranch)
-		cmd.Dir = projectRoot
-		output, err := cmd.Output()
-		if err == nil {
-			mergeBase := strings.TrimSpace(string(output))
-			cmd = exec.Command("git", "rev-list", "--count", mergeBase+"..HEAD")
-			cmd.Dir = projectRoot
-			countOutput, err := cmd.Output()
-			if err == nil {
-				count := strings.TrimSpace(string(countOutput))
-				if count != "0" {
-					log.Printf("✅ 找到与%s的分叉点: %s (%s个提交)", mainBranch, mergeBase, count)
-					return mergeBase, fmt.Sprintf("分支分叉点(vs %s, %s个提交)", mainBranch, count), nil
-				}
-			}
-		}
-	} else {
-		log.Printf("⚠️ 未找到有效主分支，跳过策略2")
+	// 策略2: 检测与最近主干的分叉点
+	log.Printf("策略2: 尝试检测与主干分支的分叉点...")
+	base.TrunkPatterns, base.PatternSource = resolveTrunkPatterns(repo, opts)
+	trunk, err := findClosestTrunk(repo, base.TrunkPatterns, currentBranch)
+	var trunkNote string
+	if err == nil {
+		base.BaseCommit = trunk.MergeBase
+		base.Trunk = trunk.Branch
+		base.Strategy = fmt.Sprintf("分支分叉点(vs %s, %d个提交)", trunk.Branch, trunk.Ahead)
+		base.Explanation = fmt.Sprintf("主干分支模式 %v（来源: %s）匹配到 %d 个候选分支，其中 %s 与 HEAD 的合并基点 %s 最近（HEAD 领先 %d 个提交），以该合并基点为基准",
+			base.TrunkPatterns, base.PatternSource, trunk.Candidates, trunk.Branch, shortCommit(trunk.MergeBase), trunk.Ahead)
+		return base
 	}
+	log.Printf("⚠️ 未找到可用的主干分叉点，跳过策略2: %v", err)
+	trunkNote = fmt.Sprintf("%v（来源: %s）；", err, base.PatternSource)
 
 	// 策略3: 工作区变更
-	cmd = exec.Command("git", "status", "--porcelain")
-	cmd.Dir = projectRoot
-	statusOutput, err := cmd.Output()
-	if err == nil && len(strings.TrimSpace(string(statusOutput))) > 0 {
-		return "", "工作区变更", nil
+	status, err := repo.Status()
+	logGitError("获取工作区状态", err)
+	if err == nil && len(status.Entries) > 0 {
+		base.Strategy = "工作区变更"
+		base.Explanation = trunkNote + "工作区存在未提交的变更，以 HEAD 为基准只检查工作区变更"
+		return base
 	}
 
 	// 策略4: 最近几次提交
 	for i := 2; i <= 5; i++ {
-		base := fmt.Sprintf("HEAD~%d", i)
-		cmd = exec.Command("git", "rev-parse", "--verify", base)
-		cmd.Dir = projectRoot
-		if err := cmd.Run(); err == nil {
-			return base, fmt.Sprintf("最近%d次提交", i), nil
+		rev := fmt.Sprintf("HEAD~%d", i)
+		if repo.RevExists(rev) {
+			base.BaseCommit = rev
+			base.Strategy = fmt.Sprintf("最近%d次提交", i)
+			base.Explanation = trunkNote + fmt.Sprintf("工作区无变更，回退为检查最近 %d 次提交（%s..HEAD）", i, rev)
+			return base
 		}
 	}
-	return "HEAD~1", "最近一次提交", nil
+	base.BaseCommit = "HEAD~1"
+	base.Strategy = "最近一次提交"
+	base.Explanation = trunkNote + "工作区无变更且提交历史较短，回退为检查最近一次提交"
+	return base
 }
 
-// getChangedGoFiles 获取变更的 Go 文件列表（工作区 + 提交范围并集）
-func getChangedGoFiles(projectRoot string) ([]string, error) {
-	// 尝试检测基准提交点（用于提交范围）
-	baseCommit, strategy, _ := detectBaseCommit(projectRoot)
-	log.Printf("使用检测策略: %s，基准提交: %s", strategy, baseCommit)
-
-	changedSet := make(map[string]struct{})
-	addLines := func(lines string) {
-		for _, raw := range strings.Split(strings.TrimSpace(lines), "\n") {
-			line := strings.TrimSpace(raw)
-			if line == "" || !strings.HasSuffix(line, ".go") {
-				continue
-			}
-			var absPath string
-			if filepath.IsAbs(line) {
-				absPath = line
-			} else {
-				absPath = filepath.Join(projectRoot, line)
-			}
-			cleanPath := filepath.Clean(absPath)
-			finalPath, err := filepath.Abs(cleanPath)
-			if err != nil {
-				continue
-			}
-			if _, err := os.Stat(finalPath); err == nil {
-				changedSet[finalPath] = struct{}{}
-			}
-		}
-	}
-
-	run := func(args ...string) string {
-		cmd := exec.Command("git", args...)
-		cmd.Dir = projectRoot
-		out, err := cmd.Output()
-		if err != nil {
-			log.Printf("git %v 失败: %v", args, err)
-			return ""
-		}
-		return string(out)
-	}
-
-	// 1) 工作区未暂存
-	addLines(run("diff", "--name-only"))
-	// 2) 工作区已暂存
-	addLines(run("diff", "--name-only", "--cached"))
-	// 3) 未跟踪的新文件
-	addLines(run("ls-files", "--others", "--exclude-standard"))
-	// 4) 提交范围（若存在）
-	if baseCommit != "" {
-		addLines(run("diff", "--name-only", baseCommit, "HEAD"))
+// shortCommit 返回提交哈希的缩写形式
+func shortCommit(commit string) string {
+	if len(commit) > 12 {
+		return commit[:12]
 	}
-
-	// 汇总
-	var changedGoFiles []string
-	for p := range changedSet {
-		changedGoFiles = append(changedGoFiles, p)
-		log.Printf("收集到变更 Go 文件: %s", p)
-	}
-	if len(changedGoFiles) == 0 {
-		return nil, fmt.Errorf("未找到任何变更的 Go 文件（工作区与提交范围均为空）")
-	}
-	log.Printf("总共找到 %d 个变更的 Go 文件（工作区+提交范围）", len(changedGoFiles))
-	return changedGoFiles, nil
+	return commit
 }
 
 // runGolangciLint 执行 golangci-lint 检查
-func runGolangciLint(projectRoot string, targets []string, targetType string, checkOnlyChanges bool, vendorMode bool) (*LintResult, error) {
-	log.Printf("开始代码检查，项目根目录: %s，检测目标: %v，类型: %s，vendor模式: %v", projectRoot, targets, targetType, vendorMode)
+// tests 为测试代码检查范围（include/exclude/only），决定 --tests 参数及结果过滤；bc 为构建标签与目标平台；limits 为资源限制
+func runGolangciLint(ctx context.Context, projectRoot string, targets []string, targetType string, checkOnlyChanges bool, vendorMode bool, tests string, bc BuildConfig, limits *ResourceLimits) (*LintResult, error) {
+	log.Printf("开始代码检查，项目根目录: %s，检测目标: %v，类型: %s，vendor模式: %v，测试范围: %s，构建配置: %s", projectRoot, targets, targetType, vendorMode, tests, bc.Label())
 
 	// 检查golangci-lint是否已安装
 	if err := checkGolangciLintInstalled(); err != nil {
@@ -443,6 +569,7 @@ brew pin golangci-lint && brew install golangci-lint@1.52.2
 curl -sSfL https://raw.githubusercontent.com/golangci/golangci-lint/master/install.sh | sh -s -- -b $(go env GOPATH)/bin v1.52.2
 
 安装完成后请确保 golangci-lint 在 PATH 环境变量中。`,
+					Severity: "error",
 					Pos: Pos{
 						Filename: "system",
 						Line:     0,
@@ -476,6 +603,8 @@ curl -sSfL https://raw.githubusercontent.com/golangci/golangci-lint/master/insta
 
 	// 添加输出格式参数
 	args = append(args, "--out-format", "json", "--print-issued-lines=false", "--print-linter-name=true")
+	args = append(args, golangciTestsArgs(tests)...)
+	args = append(args, bc.golangciArgs()...)
 
 	// 如果只检查变更，添加 --new-from-rev 参数
 	if checkOnlyChanges {
@@ -491,15 +620,8 @@ curl -sSfL https://raw.githubusercontent.com/golangci/golangci-lint/master/insta
 	log.Printf("执行命令: golangci-lint %v", args)
 	log.Printf("命令执行目录: %s", projectRoot)
 
-	// 创建命令
-	cmd := exec.Command("golangci-lint", args...)
-	cmd.Dir = projectRoot // 设置工作目录为项目根目录
-
-	// 设置环境变量
-	cmd.Env = os.Environ()
-
-	// 执行命令
-	output, cmdErr := cmd.CombinedOutput()
+	// 执行命令（工作目录为项目根目录，交叉平台检查时附带 GOOS/GOARCH，并应用资源限制与全局并发上限）
+	output, cmdErr := runGolangciCommand(ctx, projectRoot, args, bc, limits)
 
 	log.Printf("命令输出长度: %d", len(output))
 	log.Printf("命令执行错误: %v", cmdErr)
@@ -514,6 +636,7 @@ curl -sSfL https://raw.githubusercontent.com/golangci/golangci-lint/master/insta
 					{
 						FromLinter: "lint-mcp",
 						Text:       "golangci-lint 命令未找到。请确保已正确安装 golangci-lint 并且在 PATH 环境变量中。",
+						Severity:   "error",
 						Pos: Pos{
 							Filename: "system",
 							Line:     0,
@@ -537,6 +660,7 @@ curl -sSfL https://raw.githubusercontent.com/golangci/golangci-lint/master/insta
 					{
 						FromLinter: "golangci-lint",
 						Text:       fmt.Sprintf("golangci-lint 执行失败: %v", cmdErr),
+						Severity:   "error",
 						Pos: Pos{
 							Filename: "unknown",
 							Line:     0,
@@ -563,6 +687,7 @@ curl -sSfL https://raw.githubusercontent.com/golangci/golangci-lint/master/insta
 				{
 					FromLinter: "golangci-lint",
 					Text:       fmt.Sprintf("无法从golangci-lint输出中提取JSON格式数据\n原始输出前200字符: %s", string(output[:maxLen])),
+					Severity:   "error",
 					Pos: Pos{
 						Filename: "unknown",
 						Line:     0,
@@ -590,6 +715,7 @@ curl -sSfL https://raw.githubusercontent.com/golangci/golangci-lint/master/insta
 				{
 					FromLinter: "golangci-lint",
 					Text:       fmt.Sprintf("JSON解析失败: %v\n请检查golangci-lint输出格式", err),
+					Severity:   "error",
 					Pos: Pos{
 						Filename: "unknown",
 						Line:     0,
@@ -601,22 +727,19 @@ curl -sSfL https://raw.githubusercontent.com/golangci/golangci-lint/master/insta
 	}
 
 	log.Printf("解析到 %d 个问题", len(golangciOutput.Issues))
+	recordLinterReport(projectRoot, golangciOutput.Report.Linters)
 
-	return &LintResult{Issues: golangciOutput.Issues}, nil
+	return &LintResult{Issues: fingerprintIssues(projectRoot, filterIssuesByTestsMode(golangciOutput.Issues, tests))}, nil
 }
 
 // runGolangciLintWithArgs 以自定义参数运行 golangci-lint 并解析 JSON 结果
-func runGolangciLintWithArgs(projectRoot string, args []string) (*LintResult, error) {
+func runGolangciLintWithArgs(ctx context.Context, projectRoot string, args []string, bc BuildConfig, limits *ResourceLimits) (*LintResult, error) {
 	log.Printf("执行命令: golangci-lint %v", args)
 	log.Printf("命令执行目录: %s", projectRoot)
 
 	// golangci-lint 可用性检查已在服务启动时完成
 
-	cmd := exec.Command("golangci-lint", args...)
-	cmd.Dir = projectRoot
-	cmd.Env = os.Environ()
-
-	output, cmdErr := cmd.CombinedOutput()
+	output, cmdErr := runGolangciCommand(ctx, projectRoot, args, bc, limits)
 	log.Printf("命令输出长度: %d", len(output))
 	log.Printf("命令执行错误: %v", cmdErr)
 
@@ -624,7 +747,7 @@ func runGolangciLintWithArgs(projectRoot string, args []string) (*LintResult, er
 	if len(output) == 0 {
 		if cmdErr != nil {
 			log.Printf("命令无输出且有错误: %v", cmdErr)
-			return nil, fmt.Errorf("golangci-lint 执行失败: %v", cmdErr)
+			return nil, fmt.Errorf("golangci-lint 执行失败: %w", cmdErr)
 		}
 		return &LintResult{Issues: make([]Issue, 0)}, nil
 	}
@@ -642,7 +765,8 @@ func runGolangciLintWithArgs(projectRoot string, args []string) (*LintResult, er
 	}
 
 	log.Printf("成功解析到 %d 个问题", len(golangciOutput.Issues))
-	return &LintResult{Issues: golangciOutput.Issues}, nil
+	recordLinterReport(projectRoot, golangciOutput.Report.Linters)
+	return &LintResult{Issues: fingerprintIssues(projectRoot, golangciOutput.Issues)}, nil
 }
 
 // extractJSONFromOutput 从golangci-lint输出中提取JSON部分
@@ -729,6 +853,58 @@ func extractJSONFromOutput(output string) string {
 	return ""
 }
 
+// resolveBaseDir 计算检测起点目录：优先 projectPath -> files 推断
+// 如果既没有 projectPath 也没有 files，则直接给出明确指引，避免从可执行目录误扫系统盘
+// 配置了工作区白名单时，projectPath、files 及推断出的项目根目录都必须位于白名单内
+func resolveBaseDir(projectPath string, files []string) (string, error) {
+	baseDir, err := resolveRequestedDir(projectPath, files)
+	if err != nil {
+		return "", err
+	}
+	// 配置了工作区白名单时，拒绝白名单之外的路径（含经符号链接逃逸的路径）
+	if err := checkWorkspacePaths(projectPath, files, baseDir); err != nil {
+		return "", err
+	}
+	return baseDir, nil
+}
+
+// resolveRequestedDir 按 projectPath 或 files 确定起点目录（不做工作区校验）
+func resolveRequestedDir(projectPath string, files []string) (string, error) {
+	if strings.TrimSpace(projectPath) == "" && (len(files) == 0 || strings.TrimSpace(files[0]) == "") {
+		// 客户端只提供了一个 root 时以其为起点；多个 roots 时无法确定起点，由调用方指定
+		// （code_lint 在此之前已按 defaultLintTargets 检查所有 roots）
+		if roots := clientRoots.Paths(); len(roots) == 1 {
+			return roots[0], nil
+		} else if len(roots) > 1 {
+			return "", fmt.Errorf("客户端提供了 %d 个 roots，无法确定起点：请通过 projectPath 或 files 指定其中之一。可用的 roots: %s", len(roots), strings.Join(roots, ", "))
+		}
+		return "", fmt.Errorf("缺少项目起点：请提供 projectPath（项目根目录绝对路径，推荐）或 files（任一项目内文件的绝对路径），或在客户端配置 roots。例如：{\"projectPath\":\"/Users/you/path/to/project\"}。")
+	}
+
+	if projectPath != "" {
+		abs, err := filepath.Abs(projectPath)
+		if err != nil {
+			return "", fmt.Errorf("projectPath 解析失败: %v", err)
+		}
+		if !filepath.IsAbs(abs) {
+			return "", fmt.Errorf("projectPath 必须是绝对路径")
+		}
+		if stat, err := os.Stat(abs); err != nil || !stat.IsDir() {
+			return "", fmt.Errorf("projectPath 无效或不是目录: %s", abs)
+		}
+		return abs, nil
+	}
+
+	root, err := getProjectRootFromFile(files[0])
+	if err != nil {
+		return "", fmt.Errorf("从 files 推断项目根目录失败: %v", err)
+	}
+	return root, nil
+}
+
+// serverVersion 服务版本号，随 MCP initialize 响应及 lint://environment 资源返回
+const serverVersion = "1.0.16"
+
 // handleCodeLintRequest 处理智能代码检查请求
 func handleCodeLintRequest(ctx context.Context, req mcp.CallToolRequest) (result *mcp.CallToolResult, err error) {
 	// 捕获任何 panic 并转换为错误返回
@@ -747,6 +923,16 @@ func handleCodeLintRequest(ctx context.Context, req mcp.CallToolRequest) (result
 	if req.Params.Arguments == nil {
 		req.Params.Arguments = map[string]interface{}{}
 	}
+	// 未指定项目位置时，检查客户端 roots 下发现的所有项目
+	if !hasStartPath(req.Params.Arguments) {
+		if targets := defaultLintTargets(clientRoots.Paths()); len(targets) > 0 {
+			return lintDefaultTargets(ctx, req, targets), nil
+		}
+	}
+	cfg := applyToolDefaults("code_lint", req.Params.Arguments)
+	if !cfg.backendEnabled(backendGolangciLint) {
+		return backendDisabledResult(backendGolangciLint, cfg), nil
+	}
 	argsBytes, _ := json.Marshal(req.Params.Arguments)
 	if err := json.Unmarshal(argsBytes, &lintReq); err != nil {
 		return buildErrorResult(fmt.Sprintf("无效的请求参数: %v", err)), nil
@@ -762,142 +948,195 @@ func handleCodeLintRequest(ctx context.Context, req mcp.CallToolRequest) (result
 		if _, exists := req.Params.Arguments["checkOnlyChanges"]; !exists {
 			lintReq.CheckOnlyChanges = true
 		}
+		if _, exists := req.Params.Arguments["dependentsDepth"]; !exists {
+			lintReq.DependentsDepth = 1
+		}
 	}
-
-	// 如果既没有 projectPath 也没有 files，则直接给出明确指引，避免从可执行目录误扫系统盘
-	if strings.TrimSpace(lintReq.ProjectPath) == "" && (len(lintReq.Files) == 0 || strings.TrimSpace(lintReq.Files[0]) == "") {
-		msg := "缺少项目起点：请提供 projectPath（项目根目录绝对路径，推荐）或 files（任一项目内文件的绝对路径）。例如：{\"projectPath\":\"/Users/you/path/to/project\"}。"
-		return buildErrorResult(msg), nil
+	tests, err := parseTestsMode(lintReq.Tests)
+	if err != nil {
+		return buildErrorResult(err.Error()), nil
+	}
+	buildMatrix, err := parseBuildMatrix(lintReq.BuildTags, lintReq.Platforms)
+	if err != nil {
+		return buildErrorResult(err.Error()), nil
 	}
 
-	// 计算检测起点目录：优先 projectPath -> files 推断 -> 当前工作目录
-	baseDir := ""
-	if lintReq.ProjectPath != "" {
-		abs, err := filepath.Abs(lintReq.ProjectPath)
-		if err != nil {
-			return buildErrorResult(fmt.Sprintf("projectPath 解析失败: %v", err)), nil
-		}
-		if !filepath.IsAbs(abs) {
-			return buildErrorResult("projectPath 必须是绝对路径"), nil
-		}
-		if stat, err := os.Stat(abs); err != nil || !stat.IsDir() {
-			return buildErrorResult(fmt.Sprintf("projectPath 无效或不是目录: %s", abs)), nil
-		}
-		baseDir = abs
-	} else if len(lintReq.Files) > 0 && lintReq.Files[0] != "" {
-		root, err := getProjectRootFromFile(lintReq.Files[0])
-		if err != nil {
-			return buildErrorResult(fmt.Sprintf("从 files 推断项目根目录失败: %v", err)), nil
-		}
-		baseDir = root
-	} else {
-		cwd, err := os.Getwd()
-		if err != nil {
-			return buildErrorResult(fmt.Sprintf("获取当前工作目录失败: %v", err)), nil
-		}
-		abs, err := filepath.Abs(cwd)
-		if err != nil {
-			return buildErrorResult(fmt.Sprintf("转换绝对路径失败: %v", err)), nil
-		}
-		baseDir = abs
+	baseDir, err := resolveBaseDir(lintReq.ProjectPath, lintReq.Files)
+	if err != nil {
+		return buildPathErrorResult(err), nil
 	}
 	log.Printf("检测起点目录: %s", baseDir)
 
+	// 监听模式下直接使用后台维护的实时问题集
+	if liveResult, ok := live.serve(baseDir, lintReq, tests, buildMatrix, cfg); ok {
+		applySeverityPolicy(cfg, liveResult)
+		recordLintRun(baseDir, lintReq, tests, liveResult)
+		return buildToolResult(liveResult, outputConfig(ctx, cfg)), nil
+	}
+
 	// 如果 checkOnlyChanges=true，智能检测变更文件
 	if lintReq.CheckOnlyChanges {
 		log.Printf("checkOnlyChanges=true，智能检测变更文件（起点: %s）", baseDir)
 
-		// 获取最新变更的 Go 文件（工作区+提交范围）
-		changedFiles, err := getChangedGoFiles(baseDir)
+		// 获取最新变更的 Go 文件（工作区+提交范围），识别重命名与删除
+		var changedFiles []string
+		var skippedGenerated int
+		changeSet, err := getChangeSet(baseDir, ScopeOptions{TrunkBranches: lintReq.TrunkBranches, Config: cfg, SkipGenerated: !lintReq.IncludeGenerated})
 		if err != nil {
 			log.Printf("Git检测失败（起点: %s），尝试备用策略: %v", baseDir, err)
-			fallbackFiles, fallbackErr := findAllGoFiles(baseDir)
+			fallbackFiles, skipped, fallbackErr := findAllGoFiles(baseDir, cfg, lintReq.IncludeGenerated, tests)
 			if fallbackErr != nil {
 				return buildErrorResult(fmt.Sprintf("Git检测失败（起点: %s）: %v\n备用文件扫描也失败: %v\n\n请提供 projectPath 或 files 以明确项目位置。", baseDir, err, fallbackErr)), nil
 			}
 			log.Printf("使用备用策略：扫描到 %d 个Go文件（起点: %s）", len(fallbackFiles), baseDir)
 			changedFiles = fallbackFiles
+			skippedGenerated = skipped
+		} else {
+			changedFiles = filterFilesByTestsMode(changeSet.Paths(), tests)
+			skippedGenerated = len(changeSet.SkippedGenerated)
+		}
+
+		// 有文件被删除的包：包内剩余文件可能因此失效，需要按包重新检查
+		deletedPackages := make(map[string][]string)
+		var scope *ScopeInfo
+		if changeSet != nil {
+			deletedPackages = changeSet.DeletedPackages()
+			scope = changeSet.Scope()
 		}
 
-		log.Printf("智能检测到 %d 个变更的 Go 文件（起点: %s）", len(changedFiles), baseDir)
+		log.Printf("智能检测到 %d 个变更的 Go 文件、%d 个项目存在删除文件的包（起点: %s）", len(changedFiles), len(deletedPackages), baseDir)
 
 		// 按项目分组变更文件，因为变更可能涉及多个项目
-		projectFiles := make(map[string][]string)
-		for _, file := range changedFiles {
-			projectRoot, err := getProjectRootFromFile(file)
-			if err != nil {
-				log.Printf("警告：无法确定文件 %s 的项目根目录：%v", file, err)
-				continue
+		projectFiles := groupFilesByProject(changedFiles)
+
+		// 先编译检查，编译失败时直接返回结构化的编译错误
+		buildPackages := make(map[string][]string)
+		if len(changedFiles) > 0 {
+			if pkgs, err := getPackagesFromFiles(changedFiles); err == nil {
+				buildPackages = pkgs
 			}
-			projectFiles[projectRoot] = append(projectFiles[projectRoot], file)
 		}
+		mergeProjectPackages(buildPackages, deletedPackages)
+		// 每个构建配置（buildTags × platforms）各执行一遍：先编译检查，编译失败时该配置只返回结构化的编译错误
+		partial := false
+		lintPass := func(bc BuildConfig) ([]Issue, []DependentPackage) {
+			if len(buildPackages) > 0 && cfg.backendEnabled(backendGoBuild) {
+				if buildIssues := checkBuildBeforeLint(ctx, buildPackages, bc); len(buildIssues) > 0 {
+					log.Printf("编译检查失败（构建配置: %s），跳过 golangci-lint，返回 %d 个编译错误", bc.Label(), len(buildIssues))
+					partial = true
+					return buildIssues, nil
+				}
+			}
 
-		// 对每个项目的变更文件进行检查（逐文件，多策略）
-		allIssues := make([]Issue, 0)
-		for projectRoot, files := range projectFiles {
-			log.Printf("检查项目 %s 中的 %d 个变更文件", projectRoot, len(files))
+			// 对每个项目的变更文件进行检查（逐文件，多策略）
+			allIssues := make([]Issue, 0)
+			var dependents []DependentPackage
+			for projectRoot, files := range projectFiles {
+				log.Printf("检查项目 %s 中的 %d 个变更文件", projectRoot, len(files))
+
+				vendorMode := autoDetectVendorMode(projectRoot)
+				var testFiles []string
+				for _, file := range files {
+					if isTestFile(file) {
+						testFiles = append(testFiles, file)
+						continue
+					}
+					log.Printf("开始检查文件: %s (项目: %s, vendorMode: %v)", file, projectRoot, vendorMode)
 
-			vendorMode := autoDetectVendorMode(projectRoot)
-			for _, file := range files {
-				log.Printf("开始检查文件: %s (项目: %s, vendorMode: %v)", file, projectRoot, vendorMode)
+					// 尝试1：完整JSON参数 + 绝对路径
+					args1 := []string{"run"}
+					if vendorMode {
+						args1 = append(args1, "--modules-download-mode=vendor")
+					}
+					args1 = append(args1, bc.golangciArgs()...)
+					args1 = append(args1, "--out-format", "json", "--print-issued-lines=false", "--print-linter-name=true", file)
+					res1, err1 := runGolangciLintWithArgs(ctx, projectRoot, args1, bc, cfg.Limits)
+					if errors.As(err1, new(*resourceLimitError)) {
+						// 超出资源上限时换参数重试只会再次被终止
+						allIssues = append(allIssues, Issue{FromLinter: "lint-mcp", Text: err1.Error(), Severity: "error", Pos: Pos{Filename: file}})
+						continue
+					}
+					if err1 != nil {
+						log.Printf("尝试1失败: %v", err1)
+					} else if res1 != nil && len(res1.Issues) > 0 {
+						log.Printf("尝试1成功，发现 %d 个问题", len(res1.Issues))
+						allIssues = append(allIssues, res1.Issues...)
+						continue
+					}
 
-				// 尝试1：完整JSON参数 + 绝对路径
-				args1 := []string{"run"}
-				if vendorMode {
-					args1 = append(args1, "--modules-download-mode=vendor")
-				}
-				args1 = append(args1, "--out-format", "json", "--print-issued-lines=false", "--print-linter-name=true", file)
-				res1, err1 := runGolangciLintWithArgs(projectRoot, args1)
-				if err1 != nil {
-					log.Printf("尝试1失败: %v", err1)
-				} else if res1 != nil && len(res1.Issues) > 0 {
-					log.Printf("尝试1成功，发现 %d 个问题", len(res1.Issues))
-					allIssues = append(allIssues, res1.Issues...)
-					continue
-				}
+					// 尝试2：最小JSON参数 + 绝对路径
+					args2 := []string{"run"}
+					if vendorMode {
+						args2 = append(args2, "--modules-download-mode=vendor")
+					}
+					args2 = append(args2, bc.golangciArgs()...)
+					args2 = append(args2, "--out-format", "json", file)
+					res2, err2 := runGolangciLintWithArgs(ctx, projectRoot, args2, bc, cfg.Limits)
+					if err2 != nil {
+						log.Printf("尝试2失败: %v", err2)
+					} else if res2 != nil && len(res2.Issues) > 0 {
+						log.Printf("尝试2成功，发现 %d 个问题", len(res2.Issues))
+						allIssues = append(allIssues, res2.Issues...)
+						continue
+					}
 
-				// 尝试2：最小JSON参数 + 绝对路径
-				args2 := []string{"run"}
-				if vendorMode {
-					args2 = append(args2, "--modules-download-mode=vendor")
-				}
-				args2 = append(args2, "--out-format", "json", file)
-				res2, err2 := runGolangciLintWithArgs(projectRoot, args2)
-				if err2 != nil {
-					log.Printf("尝试2失败: %v", err2)
-				} else if res2 != nil && len(res2.Issues) > 0 {
-					log.Printf("尝试2成功，发现 %d 个问题", len(res2.Issues))
-					allIssues = append(allIssues, res2.Issues...)
-					continue
+					// 尝试3：最小JSON参数 + 相对路径
+					rel := file
+					if rel2, err := filepath.Rel(projectRoot, file); err == nil && !strings.HasPrefix(rel2, "..") {
+						rel = rel2
+					}
+					args3 := []string{"run"}
+					if vendorMode {
+						args3 = append(args3, "--modules-download-mode=vendor")
+					}
+					args3 = append(args3, bc.golangciArgs()...)
+					args3 = append(args3, "--out-format", "json", rel)
+					res3, err3 := runGolangciLintWithArgs(ctx, projectRoot, args3, bc, cfg.Limits)
+					if err3 != nil {
+						log.Printf("尝试3失败: %v", err3)
+					} else if res3 != nil && len(res3.Issues) > 0 {
+						log.Printf("尝试3成功，发现 %d 个问题", len(res3.Issues))
+						allIssues = append(allIssues, res3.Issues...)
+						continue
+					}
+
+					// 若三次均无，则记录一次提示（不作为硬错误）
+					log.Printf("文件 %s 三次尝试均未检出问题（vendorMode=%v）", file, vendorMode)
 				}
+				allIssues = append(allIssues, lintTestFiles(ctx, projectRoot, testFiles, vendorMode, bc, cfg.Limits)...)
 
-				// 尝试3：最小JSON参数 + 相对路径
-				rel := file
-				if rel2, err := filepath.Rel(projectRoot, file); err == nil && !strings.HasPrefix(rel2, "..") {
-					rel = rel2
+				if changeSet != nil {
+					allIssues = changeSet.FilterIssues(projectRoot, allIssues)
 				}
-				args3 := []string{"run"}
-				if vendorMode {
-					args3 = append(args3, "--modules-download-mode=vendor")
+
+				if lintReq.IncludeDependents {
+					if projectPackages, err := getPackagesFromFiles(files); err == nil {
+						issues, deps := lintDependents(ctx, projectRoot, projectPackages[projectRoot], lintReq.DependentsDepth, vendorMode, tests, bc, cfg.Limits)
+						allIssues = append(allIssues, issues...)
+						dependents = append(dependents, deps...)
+					}
 				}
-				args3 = append(args3, "--out-format", "json", rel)
-				res3, err3 := runGolangciLintWithArgs(projectRoot, args3)
-				if err3 != nil {
-					log.Printf("尝试3失败: %v", err3)
-				} else if res3 != nil && len(res3.Issues) > 0 {
-					log.Printf("尝试3成功，发现 %d 个问题", len(res3.Issues))
-					allIssues = append(allIssues, res3.Issues...)
+			}
+
+			// 对有文件被删除的包做整包检查（删除不会产生新增行，无法按变更行过滤）
+			for projectRoot, packages := range deletedPackages {
+				log.Printf("检查项目 %s 中有文件被删除的包: %v", projectRoot, packages)
+				vendorMode := autoDetectVendorMode(projectRoot)
+				result, err := runGolangciLint(ctx, projectRoot, packages, "package", false, vendorMode, tests, bc, cfg.Limits)
+				if err != nil {
+					log.Printf("检查有文件被删除的包失败（项目: %s）: %v", projectRoot, err)
 					continue
 				}
-
-				// 若三次均无，则记录一次提示（不作为硬错误）
-				log.Printf("文件 %s 三次尝试均未检出问题（vendorMode=%v）", file, vendorMode)
+				allIssues = append(allIssues, result.Issues...)
 			}
+			return allIssues, dependents
 		}
+		allIssues, dependents := runBuildMatrix(buildMatrix, lintPass)
 
-		finalResult := &LintResult{Issues: allIssues}
-		resultJSON, _ := json.Marshal(finalResult)
-		return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Type: "text", Text: string(resultJSON)}}}, nil
+		finalResult := &LintResult{Issues: dedupeIssues(allIssues), Dependents: dependents, Scope: scope, SkippedGenerated: skippedGenerated, Partial: partial}
+		applySeverityPolicy(cfg, finalResult)
+		recordLintRun(baseDir, lintReq, tests, finalResult)
+		return buildToolResult(finalResult, outputConfig(ctx, cfg)), nil
 	}
 
 	// checkOnlyChanges=false 时，使用包路径进行全面检查
@@ -906,118 +1145,132 @@ func handleCodeLintRequest(ctx context.Context, req mcp.CallToolRequest) (result
 	if err != nil {
 		return buildErrorResult(fmt.Sprintf("获取包路径失败: %v", err)), nil
 	}
-	allIssues := make([]Issue, 0)
-	for projectRoot, packages := range projectPackages {
-		log.Printf("检查项目 %s 的包: %v", projectRoot, packages)
-		vendorMode := autoDetectVendorMode(projectRoot)
-		result, err := runGolangciLint(projectRoot, packages, "package", lintReq.CheckOnlyChanges, vendorMode)
-		if err != nil {
-			msg := fmt.Sprintf("执行 golangci-lint 失败\n项目: %s\n目标: %v\nvendorMode: %v\n错误: %v", projectRoot, packages, vendorMode, err)
-			return buildErrorResult(msg), nil
+	partial := false
+	lintPass := func(bc BuildConfig) ([]Issue, []DependentPackage) {
+		if !cfg.backendEnabled(backendGoBuild) {
+			log.Printf("后端 %s 已禁用，跳过编译检查", backendGoBuild)
+		} else if buildIssues := checkBuildBeforeLint(ctx, projectPackages, bc); len(buildIssues) > 0 {
+			log.Printf("编译检查失败（构建配置: %s），跳过 golangci-lint，返回 %d 个编译错误", bc.Label(), len(buildIssues))
+			partial = true
+			return buildIssues, nil
 		}
-		allIssues = append(allIssues, result.Issues...)
-	}
-	finalResult := &LintResult{Issues: allIssues}
-	resultJSON, _ := json.Marshal(finalResult)
-	return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Type: "text", Text: string(resultJSON)}}}, nil
-}
-
-// getActualMainBranch 获取项目实际使用的主分支
-func getActualMainBranch(projectRoot string) string {
-	log.Printf("🔍 智能检测实际主分支...")
-
-	// 方法1: 检查Git默认分支配置 (最准确)
-	cmd := exec.Command("git", "symbolic-ref", "refs/remotes/origin/HEAD")
-	cmd.Dir = projectRoot
-	if output, err := cmd.Output(); err == nil {
-		defaultRef := strings.TrimSpace(string(output))
-		if parts := strings.Split(defaultRef, "/"); len(parts) >= 3 {
-			branchName := strings.Join(parts[3:], "/")
-			remoteBranch := "origin/" + branchName
-			// 验证该分支是否真实存在
-			if verifyBranchExists(projectRoot, remoteBranch) {
-				log.Printf("✅ 检测到Git默认分支: %s", remoteBranch)
-				return remoteBranch
-			}
-			// 尝试本地分支
-			if verifyBranchExists(projectRoot, branchName) {
-				log.Printf("✅ 检测到Git默认分支(本地): %s", branchName)
-				return branchName
+		allIssues := make([]Issue, 0)
+		var dependents []DependentPackage
+		for projectRoot, packages := range projectPackages {
+			log.Printf("检查项目 %s 的包: %v", projectRoot, packages)
+			vendorMode := autoDetectVendorMode(projectRoot)
+			result, err := runGolangciLint(ctx, projectRoot, packages, "package", lintReq.CheckOnlyChanges, vendorMode, tests, bc, cfg.Limits)
+			if err != nil {
+				msg := fmt.Sprintf("执行 golangci-lint 失败\n项目: %s\n目标: %v\nvendorMode: %v\n构建配置: %s\n错误: %v", projectRoot, packages, vendorMode, bc.Label(), err)
+				return []Issue{{FromLinter: "lint-mcp", Text: msg, Severity: "error", Pos: Pos{Filename: projectRoot}}}, nil
 			}
-		}
-	}
+			allIssues = append(allIssues, result.Issues...)
 
-	// 方法2: reflog历史检测 (检查当前分支是从哪里checkout出来的)
-	currentBranch := getCurrentBranchSmart(projectRoot)
-	if currentBranch != "" {
-		cmd := exec.Command("git", "reflog", "--oneline", "-n", "15")
-		cmd.Dir = projectRoot
-		if output, err := cmd.Output(); err == nil {
-			lines := strings.Split(strings.TrimSpace(string(output)), "\n")
-			for _, line := range lines {
-				if strings.Contains(line, "checkout: moving from") &&
-					strings.Contains(line, " to "+currentBranch) {
-					if idx := strings.Index(line, "moving from "); idx >= 0 {
-						remaining := line[idx+len("moving from "):]
-						if toIdx := strings.Index(remaining, " to "); toIdx >= 0 {
-							sourceBranch := remaining[:toIdx]
-							if sourceBranch != currentBranch && sourceBranch != "" {
-								// 优先检查origin/分支
-								remoteBranch := "origin/" + sourceBranch
-								if verifyBranchExists(projectRoot, remoteBranch) {
-									log.Printf("✅ 从reflog发现源分支: %s", remoteBranch)
-									return remoteBranch
-								}
-								// 检查本地分支
-								if verifyBranchExists(projectRoot, sourceBranch) {
-									log.Printf("✅ 从reflog发现源分支(本地): %s", sourceBranch)
-									return sourceBranch
-								}
-							}
-						}
-					}
-				}
+			if lintReq.IncludeDependents {
+				issues, deps := lintDependents(ctx, projectRoot, packages, lintReq.DependentsDepth, vendorMode, tests, bc, cfg.Limits)
+				allIssues = append(allIssues, issues...)
+				dependents = append(dependents, deps...)
 			}
 		}
+		return allIssues, dependents
 	}
+	allIssues, dependents := runBuildMatrix(buildMatrix, lintPass)
+	finalResult := &LintResult{Issues: dedupeIssues(allIssues), Dependents: dependents, Partial: partial}
+	applySeverityPolicy(cfg, finalResult)
+	recordLintRun(baseDir, lintReq, tests, finalResult)
+	return buildToolResult(finalResult, outputConfig(ctx, cfg)), nil
+}
 
-	// 方法3: 按优先级检查常见主分支
-	candidates := []string{"origin/main", "main", "origin/master", "master", "origin/develop", "develop"}
-	for _, branch := range candidates {
-		if verifyBranchExists(projectRoot, branch) {
-			log.Printf("✅ 找到存在的主分支: %s", branch)
-			return branch
-		}
+// lintDependents 检查依赖变更包的其他包。这些包本身没有变更，问题通常出现在未修改的调用处，
+// 因此按包全量检查而不使用 --new-from-rev 过滤；依赖包编译失败时直接返回编译错误
+func lintDependents(ctx context.Context, projectRoot string, packages []string, depth int, vendorMode bool, tests string, bc BuildConfig, limits *ResourceLimits) ([]Issue, []DependentPackage) {
+	dependents, err := expandDependents(ctx, projectRoot, packages, depth, vendorMode)
+	if err != nil {
+		log.Printf("查找依赖包失败（项目: %s）: %v", projectRoot, err)
+		return nil, nil
+	}
+	if len(dependents) == 0 {
+		return nil, nil
 	}
 
-	log.Printf("⚠️ 未能找到有效的主分支")
-	return ""
+	targets := make([]string, 0, len(dependents))
+	for _, dep := range dependents {
+		log.Printf("纳入依赖包 %s: %s", dep.Package, dep.Reason)
+		targets = append(targets, dep.Package)
+	}
+	if buildIssues := runGoBuild(ctx, projectRoot, targets, vendorMode, true, bc); len(buildIssues) > 0 {
+		return buildIssues, dependents
+	}
+	result, err := runGolangciLint(ctx, projectRoot, targets, "package", false, vendorMode, tests, bc, limits)
+	if err != nil {
+		log.Printf("检查依赖包失败（项目: %s）: %v", projectRoot, err)
+		return nil, dependents
+	}
+	return result.Issues, dependents
 }
 
-// verifyBranchExists 验证分支是否存在
-func verifyBranchExists(projectRoot, branch string) bool {
-	cmd := exec.Command("git", "rev-parse", "--verify", branch)
-	cmd.Dir = projectRoot
-	return cmd.Run() == nil
-}
+// lintTestFiles 检查变更的测试文件。测试文件依赖同目录的生产代码（外部测试包还需导入被测包），
+// 单独传入文件会产生大量类型错误，因此按所在目录整包检查，只保留这些测试文件中的问题
+func lintTestFiles(ctx context.Context, projectRoot string, files []string, vendorMode bool, bc BuildConfig, limits *ResourceLimits) []Issue {
+	if len(files) == 0 {
+		return nil
+	}
+	wanted := make(map[string]bool, len(files))
+	dirs := make(map[string]bool)
+	var packages []string
+	for _, file := range files {
+		wanted[filepath.Clean(file)] = true
+		pkg, err := toRelPackagePath(projectRoot, filepath.Dir(file))
+		if err != nil || dirs[pkg] {
+			continue
+		}
+		dirs[pkg] = true
+		packages = append(packages, pkg)
+	}
+	log.Printf("按包检查 %d 个变更的测试文件: %v", len(files), packages)
 
-// getCurrentBranchSmart 获取当前分支名
-func getCurrentBranchSmart(projectRoot string) string {
-	cmd := exec.Command("git", "rev-parse", "--abbrev-ref", "HEAD")
-	cmd.Dir = projectRoot
-	if output, err := cmd.Output(); err == nil {
-		return strings.TrimSpace(string(output))
+	result, err := runGolangciLint(ctx, projectRoot, packages, "package", false, vendorMode, testsOnly, bc, limits)
+	if err != nil {
+		log.Printf("检查测试文件失败（项目: %s）: %v", projectRoot, err)
+		return nil
 	}
-	return ""
+	var issues []Issue
+	for _, issue := range result.Issues {
+		name := issue.Pos.Filename
+		if !filepath.IsAbs(name) {
+			name = filepath.Join(projectRoot, name)
+		}
+		if wanted[filepath.Clean(name)] {
+			issues = append(issues, issue)
+		}
+	}
+	return issues
 }
 
 func main() {
+	var allowRoots rootListFlag
+	flag.Var(&allowRoots, "allow-root", "允许检查的工作区根目录（可重复指定；未指定时读取环境变量 "+allowedRootsEnv+"）")
+	maxParallelLint := flag.Int("max-parallel-lint", 0, "所有请求同时运行的 golangci-lint 进程数上限（默认读取环境变量 "+maxParallelLintEnv+"，均未设置时为 1）")
+	watch := flag.Bool("watch", false, "监听工作区文件变更，在后台重新检查受影响的包并维护实时问题集（也可设置环境变量 "+watchEnv+"=1）")
+	flag.Parse()
+
 	log.Println("启动 lint-mcp 服务 (兼容版本)...")
+	if err := initAllowedRoots(allowRoots); err != nil {
+		log.Fatalf("工作区白名单配置无效: %v", err)
+	}
+	if err := initLintSlots(*maxParallelLint); err != nil {
+		log.Fatalf("并发上限配置无效: %v", err)
+	}
+	initWatch(*watch)
 
+	hooks := &server.Hooks{}
 	s := server.NewMCPServer(
 		"lint-mcp",
-		"1.0.16",
+		serverVersion,
+		server.WithHooks(hooks),
+		server.WithResourceCapabilities(true, false),
 	)
+	registerClientRoots(s, hooks)
 
 	// 注册 code_lint 工具
 	tool := mcp.NewTool("code_lint",
@@ -1028,14 +1281,202 @@ func main() {
 		mcp.WithBoolean("checkOnlyChanges",
 			mcp.Description("是否启用智能变更检测（默认true）。将自动检测Git变更范围：未推送提交、分支分叉点或工作区变更。"),
 		),
+		mcp.WithBoolean("includeDependents",
+			mcp.Description("是否同时检查依赖变更包的其他包（默认false），结果中的 Dependents 说明每个包被纳入的原因"),
+		),
+		mcp.WithNumber("dependentsDepth",
+			mcp.Description("依赖包展开层级：1为只包含直接导入方（默认），N为传递N层，0为不限层级"),
+		),
+		mcp.WithString("tests",
+			mcp.Description("测试代码检查范围：include（默认，同时检查生产代码与 _test.go）、exclude（只检查生产代码，golangci-lint 使用 --tests=false）、only（只检查测试代码）"),
+			mcp.Enum(testsInclude, testsExclude, testsOnly),
+		),
+		mcp.WithArray("buildTags",
+			mcp.Description("构建标签组合（可选），每项为一组逗号分隔的标签，空字符串表示不加标签，如 [\"\", \"integration\"]。与 platforms 组成矩阵逐一检查，合并结果中的 Configurations 标注问题出现的配置"),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+		mcp.WithArray("platforms",
+			mcp.Description("目标平台（可选），形如 [\"linux/amd64\", \"darwin/arm64\", \"windows/amd64\"]。以 GOOS/GOARCH 交叉类型检查，不执行任何代码"),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+		mcp.WithBoolean("includeGenerated",
+			mcp.Description("是否包含生成代码（默认false）。带有 \"// Code generated ... DO NOT EDIT.\" 标记或匹配 generatedPatterns 的文件默认跳过，跳过数量见结果中的 SkippedGenerated"),
+		),
+		mcp.WithArray("trunkBranches",
+			mcp.Description(trunkBranchesDescription),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+	)
+
+	s.AddTool(tool, coordinated("code_lint", handleCodeLintRequest))
+
+	// 注册 code_format 工具
+	formatTool := mcp.NewTool("code_format",
+		mcp.WithDescription("Go代码格式检查工具。按 goimports 规则（gofmt 格式化，补全缺失与删除未使用的 import）检查变更文件，返回 unified diff，可选原地改写。"),
+		mcp.WithString("projectPath",
+			mcp.Description("项目根目录（可选，优先作为检测起点）"),
+		),
+		mcp.WithBoolean("checkOnlyChanges",
+			mcp.Description("是否只检查变更文件（默认true）。为false时检查 files 或项目下全部Go文件。"),
+		),
+		mcp.WithBoolean("write",
+			mcp.Description("是否将格式化结果原地写回文件（默认false）"),
+		),
+		mcp.WithBoolean("includeGenerated",
+			mcp.Description("是否包含生成代码（默认false）。带有 \"// Code generated ... DO NOT EDIT.\" 标记或匹配 generatedPatterns 的文件默认跳过，跳过数量见结果中的 SkippedGenerated"),
+		),
+		mcp.WithArray("trunkBranches",
+			mcp.Description(trunkBranchesDescription),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+	)
+
+	s.AddTool(formatTool, coordinated("code_format", handleCodeFormatRequest))
+
+	// 注册 code_vulncheck 工具
+	vulnTool := mcp.NewTool("code_vulncheck",
+		mcp.WithDescription("Go依赖漏洞扫描工具。基于 govulncheck 和本地漏洞数据库（不访问网络）分析模块依赖，报告代码中可达的漏洞符号及调用栈。"),
+		mcp.WithString("projectPath",
+			mcp.Description("项目根目录（可选，优先作为检测起点）"),
+		),
+		mcp.WithBoolean("checkOnlyChanges",
+			mcp.Description("是否只扫描变更文件所属的模块（默认true）"),
+		),
+		mcp.WithString("dbPath",
+			mcp.Description("本地漏洞数据库目录（可选，默认读取环境变量 LINT_MCP_VULNDB）"),
+		),
+		mcp.WithArray("trunkBranches",
+			mcp.Description(trunkBranchesDescription),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+	)
+
+	s.AddTool(vulnTool, coordinated("code_vulncheck", handleCodeVulnCheckRequest))
+
+	// 注册 code_test 工具
+	testTool := mcp.NewTool("code_test",
+		mcp.WithDescription("Go测试运行工具。智能检测变更文件所在的包（可选包含依赖这些包的其他包），执行 go test -json 并返回结构化的通过/失败/跳过结果。"),
+		mcp.WithString("projectPath",
+			mcp.Description("项目根目录（可选，优先作为检测起点）"),
+		),
+		mcp.WithBoolean("checkOnlyChanges",
+			mcp.Description("是否只测试变更文件所在的包（默认true）。为false时测试 files 所在包或整个模块。"),
+		),
+		mcp.WithBoolean("includeDependents",
+			mcp.Description("是否同时测试依赖变更包的其他包（默认false）"),
+		),
+		mcp.WithNumber("dependentsDepth",
+			mcp.Description("依赖包展开层级：1为只包含直接导入方（默认），N为传递N层，0为不限层级"),
+		),
+		mcp.WithString("timeout",
+			mcp.Description("go test 超时时间，如 30s、5m（默认10m）"),
+		),
+		mcp.WithArray("trunkBranches",
+			mcp.Description(trunkBranchesDescription),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+	)
+
+	s.AddTool(testTool, coordinated("code_test", handleCodeTestRequest))
+
+	// 注册 code_build 工具
+	buildTool := mcp.NewTool("code_build",
+		mcp.WithDescription("Go编译检查工具。对变更文件所在的包执行 go build（并用 go vet 类型检查测试代码），将编译错误解析为带文件/行/列的结构化问题。"),
+		mcp.WithString("projectPath",
+			mcp.Description("项目根目录（可选，优先作为检测起点）"),
+		),
+		mcp.WithBoolean("checkOnlyChanges",
+			mcp.Description("是否只检查变更文件所在的包（默认true）。为false时检查 files 所在包或整个模块。"),
+		),
+		mcp.WithBoolean("includeTests",
+			mcp.Description("是否同时通过 go vet 类型检查测试代码（默认true）"),
+		),
+		mcp.WithBoolean("includeDependents",
+			mcp.Description("是否同时检查依赖变更包的其他包（默认false）"),
+		),
+		mcp.WithNumber("dependentsDepth",
+			mcp.Description("依赖包展开层级：1为只包含直接导入方（默认），N为传递N层，0为不限层级"),
+		),
+		mcp.WithArray("trunkBranches",
+			mcp.Description(trunkBranchesDescription),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+	)
+
+	s.AddTool(buildTool, coordinated("code_build", handleCodeBuildRequest))
+
+	// 注册 lint_config 工具
+	configTool := mcp.NewTool("lint_config",
+		mcp.WithDescription("查看 lint-mcp 生效配置。合并内置默认值、用户级配置与项目 .lint-mcp.yaml（从项目目录逐级向上查找），返回生效配置及每个配置项的来源。"),
+		mcp.WithString("projectPath",
+			mcp.Description("项目根目录（可选，作为配置查找起点）"),
+		),
+	)
+	s.AddTool(configTool, handleLintConfigRequest)
+
+	// 注册 lint_explain 工具
+	explainTool := mcp.NewTool("lint_explain",
+		mcp.WithDescription("解释检查问题：根据 linter 名称（问题的 FromLinter）及可选的规则名或问题文本，返回内置文档中的说明、修复理由、错误/正确示例，以及 //nolint 写法和关闭该规则的配置。文档编译在服务中，离线可用。"),
+		mcp.WithString("linter",
+			mcp.Required(),
+			mcp.Description("linter 名称，如 gocritic、revive、staticcheck、gosec"),
+		),
+		mcp.WithString("rule",
+			mcp.Description("规则名（可选），如 ifElseChain、exported、SA4006、G104"),
+		),
+		mcp.WithString("message",
+			mcp.Description("问题文本（可选），未指定 rule 时从文本开头的 \"规则名:\" 中提取"),
+		),
+	)
+
+	s.AddTool(explainTool, handleLintExplainRequest)
+
+	// 注册 lint_history 工具
+	historyTool := mcp.NewTool("lint_history",
+		mcp.WithDescription("查看项目最近的 code_lint 检查记录：每次检查的参数、问题数（按 linter 统计），以及与上一次相同范围检查相比新增、已修复、未变化的问题数。"),
+		mcp.WithString("projectPath",
+			mcp.Description("项目根目录（与 code_lint 的检测起点一致）"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("返回的记录数（默认10）"),
+		),
 	)
+	s.AddTool(historyTool, handleLintHistoryRequest)
+
+	// 注册 lint_nolint_audit 工具
+	nolintTool := mcp.NewTool("lint_nolint_audit",
+		mcp.WithDescription("审计 //nolint 指令：盘点范围内的全部指令，标出未指定 linter、缺少说明原因或格式不规范的指令，通过 nolintlint 找出未使用的指令，并标出当前分支（相对主干基准）新增的指令，便于复核屏蔽问题而非修复问题的改动。"),
+		mcp.WithArray("files",
+			mcp.Description("参考文件列表（可选，用于确定项目位置）"),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+		mcp.WithString("projectPath",
+			mcp.Description("项目根目录（可选，优先作为检测起点）"),
+		),
+		mcp.WithBoolean("checkOnlyChanges",
+			mcp.Description("是否只盘点变更文件中的指令（默认false，盘点起点目录下的全部 Go 文件）。无论取值，变更行上的指令都会标记为 New"),
+		),
+		mcp.WithArray("trunkBranches",
+			mcp.Description("主干分支模式（可选），用于确定变更范围，规则与 code_lint 相同"),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+		mcp.WithBoolean("checkUnused",
+			mcp.Description("是否运行 golangci-lint（启用 nolintlint）检查未使用的指令（默认true）"),
+		),
+	)
+	s.AddTool(nolintTool, coordinated("lint_nolint_audit", handleNolintAuditRequest))
+
+	log.Println("工具注册成功: code_lint, code_format, code_vulncheck, code_test, code_build, lint_config, lint_explain, lint_history, lint_nolint_audit")
 
-	s.AddTool(tool, handleCodeLintRequest)
+	registerResources(s)
+	registerLiveResource(s)
+	log.Println("资源注册成功: lint://project/{path}/scope, lint://project/{path}/config, lint://environment, lint://live")
 
-	log.Println("工具注册成功: code_lint")
+	registerPrompts(s)
+	log.Println("提示注册成功: review_my_changes, fix_lint_issues, explain_linter")
 	log.Println("服务就绪，等待连接...")
 
-	if err := server.ServeStdio(s); err != nil {
+	if err := serveStdio(s); err != nil {
 		log.Printf("服务错误: %v\n", err)
 	}
 }
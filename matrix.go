@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// BuildConfig 一次检查使用的构建约束组合：构建标签与目标平台。
+// 交叉平台只做类型检查（go build -o /dev/null、go vet、golangci-lint），不会执行任何产物
+type BuildConfig struct {
+	Tags   []string `json:"Tags,omitempty"`
+	GOOS   string   `json:"GOOS,omitempty"`
+	GOARCH string   `json:"GOARCH,omitempty"`
+}
+
+// isDefault 判断是否为宿主平台、无额外标签的默认配置
+func (c BuildConfig) isDefault() bool {
+	return len(c.Tags) == 0 && c.GOOS == "" && c.GOARCH == ""
+}
+
+// Label 返回用于标注问题来源的配置名，如 "linux/amd64 tags=integration,cgo"
+func (c BuildConfig) Label() string {
+	var parts []string
+	if c.GOOS != "" {
+		parts = append(parts, c.GOOS+"/"+c.GOARCH)
+	}
+	if len(c.Tags) > 0 {
+		parts = append(parts, "tags="+strings.Join(c.Tags, ","))
+	}
+	if len(parts) == 0 {
+		return "default"
+	}
+	return strings.Join(parts, " ")
+}
+
+// Env 返回执行 go / golangci-lint 时使用的环境变量
+func (c BuildConfig) Env() []string {
+	env := os.Environ()
+	if c.GOOS != "" {
+		env = append(env, "GOOS="+c.GOOS, "GOARCH="+c.GOARCH)
+	}
+	return env
+}
+
+// goTagsArgs 返回 go build / go vet 的 -tags 参数
+func (c BuildConfig) goTagsArgs() []string {
+	if len(c.Tags) == 0 {
+		return nil
+	}
+	return []string{"-tags", strings.Join(c.Tags, ",")}
+}
+
+// golangciArgs 返回 golangci-lint 的 --build-tags 参数
+func (c BuildConfig) golangciArgs() []string {
+	if len(c.Tags) == 0 {
+		return nil
+	}
+	return []string{"--build-tags", strings.Join(c.Tags, ",")}
+}
+
+// parseBuildMatrix 由 buildTags 与 platforms 参数生成检查矩阵（两者的笛卡尔积）
+// buildTags 每项是一组以逗号或空格分隔的标签（空字符串表示不加标签），platforms 每项形如 "linux/amd64"
+// 两者都未指定时只返回一个默认配置
+func parseBuildMatrix(buildTags, platforms []string) ([]BuildConfig, error) {
+	tagSets := [][]string{nil}
+	if len(buildTags) > 0 {
+		tagSets = tagSets[:0]
+		seen := make(map[string]bool)
+		for _, entry := range buildTags {
+			tags := strings.FieldsFunc(entry, func(r rune) bool { return r == ',' || r == ' ' })
+			key := strings.Join(tags, ",")
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			tagSets = append(tagSets, tags)
+		}
+	}
+
+	type platform struct{ goos, goarch string }
+	targets := []platform{{}}
+	if len(platforms) > 0 {
+		targets = targets[:0]
+		seen := make(map[platform]bool)
+		for _, entry := range platforms {
+			parts := strings.Split(strings.TrimSpace(entry), "/")
+			if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+				return nil, fmt.Errorf("platforms 取值无效: %q（应为 GOOS/GOARCH，如 linux/amd64）", entry)
+			}
+			p := platform{goos: parts[0], goarch: parts[1]}
+			if seen[p] {
+				continue
+			}
+			seen[p] = true
+			targets = append(targets, p)
+		}
+	}
+
+	configs := make([]BuildConfig, 0, len(tagSets)*len(targets))
+	for _, p := range targets {
+		for _, tags := range tagSets {
+			configs = append(configs, BuildConfig{Tags: tags, GOOS: p.goos, GOARCH: p.goarch})
+		}
+	}
+	return configs, nil
+}
+
+// issueKey 用于在不同配置的结果之间识别同一个问题
+func issueKey(issue Issue) string {
+	return fmt.Sprintf("%s\x00%d\x00%d\x00%s\x00%s", issue.Pos.Filename, issue.Pos.Line, issue.Pos.Column, issue.FromLinter, issue.Text)
+}
+
+// runBuildMatrix 依次在每个构建配置下执行检查并合并结果
+// 只有默认配置时原样返回；否则相同问题只保留一条，Configurations 列出出现该问题的全部配置
+func runBuildMatrix(configs []BuildConfig, run func(BuildConfig) ([]Issue, []DependentPackage)) ([]Issue, []DependentPackage) {
+	if len(configs) == 1 && configs[0].isDefault() {
+		return run(configs[0])
+	}
+
+	merged := make([]Issue, 0)
+	index := make(map[string]int)
+	var dependents []DependentPackage
+	seenDependents := make(map[string]bool)
+	for _, bc := range configs {
+		label := bc.Label()
+		log.Printf("按构建配置 %s 执行检查", label)
+		issues, deps := run(bc)
+		for _, issue := range issues {
+			key := issueKey(issue)
+			if i, ok := index[key]; ok {
+				// 同一配置内重复出现的问题不重复标注
+				merged[i].Configurations = mergeConfigurations(merged[i].Configurations, []string{label})
+				continue
+			}
+			issue.Configurations = []string{label}
+			index[key] = len(merged)
+			merged = append(merged, issue)
+		}
+		for _, dep := range deps {
+			key := dep.ProjectRoot + "\x00" + dep.Package
+			if !seenDependents[key] {
+				seenDependents[key] = true
+				dependents = append(dependents, dep)
+			}
+		}
+	}
+	log.Printf("构建矩阵共 %d 个配置，合并后 %d 个问题", len(configs), len(merged))
+	return merged, dependents
+}
@@ -0,0 +1,336 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// nolintAuditLinter 审计结果中问题的 FromLinter
+const nolintAuditLinter = "nolint-audit"
+
+// nolintRegex 匹配 //nolint 指令：nolint 之后只能是 linter 列表，其后为结尾、空白或紧接的 // 说明
+var nolintRegex = regexp.MustCompile(`^//(\s*)nolint(:[^\s/]*)?((?:\s|//).*)?$`)
+
+// NolintAuditRequest lint_nolint_audit 工具参数
+type NolintAuditRequest struct {
+	Files            []string `json:"files" description:"参考文件列表（可选，用于确定项目位置）"`
+	ProjectPath      string   `json:"projectPath" description:"项目根目录（可选，优先作为检测起点）"`
+	CheckOnlyChanges bool     `json:"checkOnlyChanges" description:"是否只盘点变更文件中的指令（默认false，盘点起点目录下的全部 Go 文件）"`
+	TrunkBranches    []string `json:"trunkBranches" description:"主干分支模式（可选），用于确定变更范围"`
+	// 未使用的指令需要运行 golangci-lint（启用 nolintlint）才能判断
+	CheckUnused bool `json:"checkUnused" description:"是否通过 nolintlint 检查未使用的指令（默认true）"`
+}
+
+// NolintDirective 一条 //nolint 指令
+type NolintDirective struct {
+	File        string   `json:"File"`
+	Line        int      `json:"Line"`
+	Text        string   `json:"Text"`
+	Linters     []string `json:"Linters,omitempty"` // 为空表示屏蔽所有 linter
+	Explanation string   `json:"Explanation,omitempty"`
+	// New 位于当前分支新增或修改的行（相对变更检测的基准）
+	New bool `json:"New,omitempty"`
+	// Unused nolintlint 报告该指令没有屏蔽任何问题
+	Unused   bool     `json:"Unused,omitempty"`
+	Problems []string `json:"Problems,omitempty"`
+}
+
+// NolintAuditSummary 审计统计
+type NolintAuditSummary struct {
+	Total              int `json:"Total"`
+	MissingLinter      int `json:"MissingLinter"`
+	MissingExplanation int `json:"MissingExplanation"`
+	Malformed          int `json:"Malformed"`
+	Unused             int `json:"Unused"`
+	New                int `json:"New"`
+}
+
+// NolintAuditResult lint_nolint_audit 工具结果
+type NolintAuditResult struct {
+	Issues     []Issue            `json:"Issues"` // 每个有问题的指令一条
+	Directives []NolintDirective  `json:"Directives"`
+	Summary    NolintAuditSummary `json:"Summary"`
+	Scope      *ScopeInfo         `json:"Scope,omitempty"`
+	// UnusedChecked 是否完成了未使用指令的检查（未请求或 golangci-lint 执行失败时为 false）
+	UnusedChecked bool `json:"UnusedChecked"`
+}
+
+// parseNolintDirectives 解析文件注释中的 //nolint 指令（忽略字符串中的同名文本）
+func parseNolintDirectives(file string) ([]NolintDirective, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, file, nil, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	var directives []NolintDirective
+	for _, group := range f.Comments {
+		for _, c := range group.List {
+			d, ok := parseNolintComment(c.Text)
+			if !ok {
+				continue
+			}
+			d.File = file
+			d.Line = fset.Position(c.Slash).Line
+			directives = append(directives, d)
+		}
+	}
+	return directives, nil
+}
+
+// parseNolintComment 解析单条注释文本，返回其中的 //nolint 指令（不含文件与行号）；不是指令时返回 false
+func parseNolintComment(text string) (NolintDirective, bool) {
+	m := nolintRegex.FindStringSubmatch(text)
+	if m == nil {
+		return NolintDirective{}, false
+	}
+	rest := strings.TrimSpace(m[3])
+	if m[1] != "" && m[2] == "" && rest != "" && !strings.HasPrefix(rest, "//") {
+		// 如 "// nolint is discouraged"：以 nolint 开头的普通注释
+		return NolintDirective{}, false
+	}
+	d := NolintDirective{Text: text}
+	for _, name := range strings.Split(strings.TrimPrefix(m[2], ":"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			d.Linters = append(d.Linters, name)
+		}
+	}
+	if strings.HasPrefix(rest, "//") {
+		d.Explanation = strings.TrimSpace(strings.TrimPrefix(rest, "//"))
+	}
+	if m[1] != "" {
+		d.Problems = append(d.Problems, "// 与 nolint 之间有空格，不是机器可读的指令格式，nolintlint 会报告（应写作 //nolint:linter）")
+	}
+	if len(d.Linters) == 0 || containsString(d.Linters, "all") {
+		d.Problems = append(d.Problems, "未指定 linter，会屏蔽该处的所有问题（应写作 //nolint:linter）")
+	}
+	if d.Explanation == "" {
+		d.Problems = append(d.Problems, "缺少说明原因（应写作 //nolint:linter // 原因）")
+	}
+	return d, true
+}
+
+// changedLinesOf 返回变更文件中新增或修改过的行；新文件返回 nil 且 all=true
+func changedLinesOf(cs *ChangeSet, f ChangedFile) (lines map[int]bool, all bool) {
+	if f.lineMap != nil {
+		return f.lineMap.changedLines, false
+	}
+	if f.Status == "A" {
+		return nil, true
+	}
+	// 找到文件所属的仓库（嵌套仓库优先）及其基准
+	var base *RepoBase
+	for i := range cs.Repos {
+		if isWithinDir(cs.Repos[i].Root, f.Path) && (base == nil || len(cs.Repos[i].Root) > len(base.Root)) {
+			base = &cs.Repos[i]
+		}
+	}
+	if base == nil {
+		return nil, false
+	}
+	repo := newGitRepo(base.Root)
+	top, err := repo.TopLevel()
+	if err != nil {
+		return nil, false
+	}
+	rel, err := filepath.Rel(top, f.Path)
+	if err != nil {
+		return nil, false
+	}
+	rev := base.BaseCommit
+	if rev == "" {
+		rev = "HEAD"
+	}
+	old, err := repo.ShowFile(rev, filepath.ToSlash(rel))
+	if err != nil {
+		// 基准中不存在该文件：整个文件都是新增的
+		return nil, true
+	}
+	current, err := os.ReadFile(f.Path)
+	if err != nil {
+		return nil, false
+	}
+	return buildLineMap(splitLines(string(old)), splitLines(string(current))).changedLines, false
+}
+
+// findUnusedNolint 启用 nolintlint 运行 golangci-lint，返回未使用的指令位置（文件:行）
+func findUnusedNolint(ctx context.Context, files []string, limits *ResourceLimits) (map[string]bool, error) {
+	projectPackages, err := getPackagesFromFiles(files)
+	if err != nil {
+		return nil, err
+	}
+	unused := make(map[string]bool)
+	for projectRoot, packages := range projectPackages {
+		sort.Strings(packages)
+		args := []string{"run"}
+		if autoDetectVendorMode(projectRoot) {
+			args = append(args, "--modules-download-mode=vendor")
+		}
+		args = append(args, "--out-format", "json", "--print-issued-lines=false", "--print-linter-name=true", "--enable", "nolintlint")
+		args = append(args, golangciTestsArgs(testsInclude)...)
+		args = append(args, packages...)
+		result, err := runGolangciLintWithArgs(ctx, projectRoot, args, BuildConfig{}, limits)
+		if err != nil {
+			return nil, fmt.Errorf("项目 %s: %w", projectRoot, err)
+		}
+		for _, issue := range result.Issues {
+			if issue.FromLinter != "nolintlint" || !(issue.ExpectNoLint || strings.Contains(issue.Text, "is unused")) {
+				continue
+			}
+			filename := issue.Pos.Filename
+			if !filepath.IsAbs(filename) {
+				filename = filepath.Join(projectRoot, filename)
+			}
+			unused[fmt.Sprintf("%s:%d", filepath.Clean(filename), issue.Pos.Line)] = true
+		}
+	}
+	return unused, nil
+}
+
+// handleNolintAuditRequest 盘点 //nolint 指令：缺少 linter 名称或说明的、未使用的，以及当前分支新增的
+func handleNolintAuditRequest(ctx context.Context, req mcp.CallToolRequest) (result *mcp.CallToolResult, err error) {
+	// 捕获任何 panic 并转换为错误返回
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("发生 panic: %v", r)
+			result = buildErrorResult(fmt.Sprintf("内部错误: %v", r))
+			err = nil
+		}
+	}()
+
+	log.Printf("收到 nolint 审计请求: name=%s args=%v", req.Params.Name, req.Params.Arguments)
+
+	var auditReq NolintAuditRequest
+	if req.Params.Arguments == nil {
+		req.Params.Arguments = map[string]interface{}{}
+	}
+	cfg := applyToolDefaults("lint_nolint_audit", req.Params.Arguments)
+	argsBytes, _ := json.Marshal(req.Params.Arguments)
+	if err := json.Unmarshal(argsBytes, &auditReq); err != nil {
+		return buildErrorResult(fmt.Sprintf("无效的请求参数: %v", err)), nil
+	}
+	if _, exists := req.Params.Arguments["checkUnused"]; !exists {
+		auditReq.CheckUnused = true
+	}
+
+	baseDir, err := resolveBaseDir(auditReq.ProjectPath, auditReq.Files)
+	if err != nil {
+		return buildPathErrorResult(err), nil
+	}
+
+	// 变更范围：用于标记新增的指令，checkOnlyChanges=true 时同时限定盘点范围
+	changeSet, csErr := getChangeSet(baseDir, ScopeOptions{TrunkBranches: auditReq.TrunkBranches, Config: cfg, SkipGenerated: true})
+	if csErr != nil {
+		log.Printf("变更检测失败（起点: %s），不标记新增指令: %v", baseDir, csErr)
+	}
+
+	var files []string
+	if auditReq.CheckOnlyChanges {
+		if changeSet == nil {
+			return buildErrorResult(fmt.Sprintf("变更检测失败（起点: %s）: %v", baseDir, csErr)), nil
+		}
+		files = changeSet.Paths()
+	} else {
+		all, _, err := findAllGoFiles(baseDir, cfg, false, testsInclude)
+		if err != nil {
+			log.Printf("扫描 Go 文件失败（起点: %s）: %v", baseDir, err)
+		}
+		files = all
+	}
+
+	audit := &NolintAuditResult{Issues: []Issue{}, Directives: []NolintDirective{}}
+	if changeSet != nil {
+		audit.Scope = changeSet.Scope()
+	}
+	changedFiles := make(map[string]ChangedFile)
+	if changeSet != nil {
+		for _, f := range changeSet.Files {
+			changedFiles[f.Path] = f
+		}
+	}
+
+	var directiveFiles []string
+	for _, file := range files {
+		directives, err := parseNolintDirectives(file)
+		if err != nil {
+			log.Printf("解析文件 %s 失败: %v", file, err)
+			continue
+		}
+		if len(directives) == 0 {
+			continue
+		}
+		directiveFiles = append(directiveFiles, file)
+		if f, ok := changedFiles[file]; ok {
+			lines, all := changedLinesOf(changeSet, f)
+			for i := range directives {
+				directives[i].New = all || lines[directives[i].Line]
+			}
+		}
+		audit.Directives = append(audit.Directives, directives...)
+	}
+
+	if auditReq.CheckUnused && len(directiveFiles) > 0 {
+		unused, err := findUnusedNolint(ctx, directiveFiles, cfg.Limits)
+		if err != nil {
+			log.Printf("检查未使用的 nolint 指令失败: %v", err)
+		} else {
+			audit.UnusedChecked = true
+			for i := range audit.Directives {
+				d := &audit.Directives[i]
+				if unused[fmt.Sprintf("%s:%d", filepath.Clean(d.File), d.Line)] {
+					d.Unused = true
+					d.Problems = append(d.Problems, "未使用：该处没有需要屏蔽的问题，应删除")
+				}
+			}
+		}
+	}
+
+	for _, d := range audit.Directives {
+		audit.Summary.Total++
+		if len(d.Linters) == 0 || containsString(d.Linters, "all") {
+			audit.Summary.MissingLinter++
+		}
+		if d.Explanation == "" {
+			audit.Summary.MissingExplanation++
+		}
+		if !strings.HasPrefix(d.Text, "//nolint") {
+			audit.Summary.Malformed++
+		}
+		if d.Unused {
+			audit.Summary.Unused++
+		}
+		problems := d.Problems
+		if d.New {
+			audit.Summary.New++
+			// 新增的指令往往是为了让检查通过而屏蔽问题，需要复核
+			problems = append([]string{"当前分支新增的 nolint 指令，请确认是否应修复问题而不是屏蔽"}, problems...)
+		}
+		if len(problems) == 0 {
+			continue
+		}
+		// 格式正确、已使用的新增指令只需复核（info）；格式不规范或未使用的指令需要修改（warning）
+		severity := severityInfo
+		if len(d.Problems) > 0 {
+			severity = severityWarning
+		}
+		audit.Issues = append(audit.Issues, Issue{
+			FromLinter: nolintAuditLinter,
+			Text:       fmt.Sprintf("%s：%s", d.Text, strings.Join(problems, "；")),
+			Severity:   severity,
+			Pos:        Pos{Filename: d.File, Line: d.Line},
+		})
+	}
+	log.Printf("nolint 审计完成（起点: %s）：%d 条指令，缺少 linter %d，缺少说明 %d，未使用 %d，新增 %d",
+		baseDir, audit.Summary.Total, audit.Summary.MissingLinter, audit.Summary.MissingExplanation, audit.Summary.Unused, audit.Summary.New)
+	return buildToolResult(audit, outputConfig(ctx, cfg)), nil
+}
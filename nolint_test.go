@@ -0,0 +1,81 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseNolintComment(t *testing.T) {
+	const (
+		spaced      = "// 与 nolint 之间有空格，不是机器可读的指令格式，nolintlint 会报告（应写作 //nolint:linter）"
+		noLinter    = "未指定 linter，会屏蔽该处的所有问题（应写作 //nolint:linter）"
+		noReasoning = "缺少说明原因（应写作 //nolint:linter // 原因）"
+	)
+	tests := []struct {
+		name string
+		text string
+		want *NolintDirective // nil 表示不是指令
+	}{
+		{
+			name: "以 nolint 开头的普通注释",
+			text: "// nolint is discouraged",
+			want: nil,
+		},
+		{
+			name: "nolintlint 不是指令",
+			text: "//nolintlint",
+			want: nil,
+		},
+		{
+			name: "nolint 后紧跟其他单词",
+			text: "//nolinter:foo",
+			want: nil,
+		},
+		{
+			name: "多个 linter 带说明",
+			text: "//nolint:a,b // why",
+			want: &NolintDirective{Linters: []string{"a", "b"}, Explanation: "why"},
+		},
+		{
+			name: "说明紧跟 linter 列表",
+			text: "//nolint:errcheck//reason",
+			want: &NolintDirective{Linters: []string{"errcheck"}, Explanation: "reason"},
+		},
+		{
+			name: "未指定 linter 且缺少说明",
+			text: "//nolint",
+			want: &NolintDirective{Problems: []string{noLinter, noReasoning}},
+		},
+		{
+			name: "all 视为未指定 linter",
+			text: "//nolint:all // legacy",
+			want: &NolintDirective{Linters: []string{"all"}, Explanation: "legacy", Problems: []string{noLinter}},
+		},
+		{
+			name: "// 与 nolint 之间有空格",
+			text: "// nolint:errcheck // why",
+			want: &NolintDirective{Linters: []string{"errcheck"}, Explanation: "why", Problems: []string{spaced}},
+		},
+		{
+			name: "空格后只有 nolint",
+			text: "// nolint",
+			want: &NolintDirective{Problems: []string{spaced, noLinter, noReasoning}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseNolintComment(tt.text)
+			if tt.want == nil {
+				if ok {
+					t.Errorf("parseNolintComment(%q) = %+v, want no directive", tt.text, got)
+				}
+				return
+			}
+			want := *tt.want
+			want.Text = tt.text
+			if !ok || !reflect.DeepEqual(got, want) {
+				t.Errorf("parseNolintComment(%q) = %+v, %v, want %+v", tt.text, got, ok, want)
+			}
+		})
+	}
+}
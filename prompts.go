@@ -0,0 +1,375 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// 提示中每个问题附带的源码上下文行数，以及默认最多展开的问题数
+const (
+	promptExcerptContext = 3
+	promptDefaultIssues  = 20
+)
+
+// registerPrompts 注册预置提示：由服务端统一收集变更范围、当前问题和源码片段，避免各团队各自拼装提示
+func registerPrompts(s *server.MCPServer) {
+	s.AddPrompt(mcp.NewPrompt("review_my_changes",
+		mcp.WithPromptDescription("审查当前分支的变更：附带变更范围及其选择依据、变更文件列表、code_lint 发现的问题和相关源码片段"),
+		mcp.WithArgument("projectPath",
+			mcp.ArgumentDescription("项目根目录的绝对路径"),
+			mcp.RequiredArgument(),
+		),
+		mcp.WithArgument("trunkBranches",
+			mcp.ArgumentDescription("主干分支模式，逗号分隔（可选，如 main,release/*）"),
+		),
+	), handleReviewMyChangesPrompt)
+
+	s.AddPrompt(mcp.NewPrompt("fix_lint_issues",
+		mcp.WithPromptDescription("修复变更代码中的检查问题：附带问题列表与源码片段，并给出修复约束"),
+		mcp.WithArgument("projectPath",
+			mcp.ArgumentDescription("项目根目录的绝对路径"),
+			mcp.RequiredArgument(),
+		),
+		mcp.WithArgument("linter",
+			mcp.ArgumentDescription("只处理指定 linter 的问题（可选）"),
+		),
+		mcp.WithArgument("maxIssues",
+			mcp.ArgumentDescription(fmt.Sprintf("最多展开的问题数（可选，默认 %d）", promptDefaultIssues)),
+		),
+	), handleFixLintIssuesPrompt)
+
+	s.AddPrompt(mcp.NewPrompt("explain_linter",
+		mcp.WithPromptDescription("解释某个 linter 的检查目的、在本项目中是否启用，以及它在当前变更中报告的问题"),
+		mcp.WithArgument("linter",
+			mcp.ArgumentDescription("linter 名称，如 errcheck、staticcheck"),
+			mcp.RequiredArgument(),
+		),
+		mcp.WithArgument("projectPath",
+			mcp.ArgumentDescription("项目根目录的绝对路径（可选，提供时附带该 linter 在当前变更中的问题）"),
+		),
+	), handleExplainLinterPrompt)
+}
+
+// promptLintContext 提示所需的检查上下文
+type promptLintContext struct {
+	BaseDir   string
+	ChangeSet *ChangeSet
+	Result    *LintResult
+	RawResult string // 结果无法按 JSON 解析时（如配置了 text 输出）直接嵌入原文
+}
+
+// collectPromptLintContext 计算变更范围并以默认参数运行 code_lint
+func collectPromptLintContext(ctx context.Context, projectPath string, trunkBranches []string) (*promptLintContext, error) {
+	baseDir, err := resolveBaseDir(projectPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	pc := &promptLintContext{BaseDir: baseDir}
+
+	cfg := loadEffectiveConfig(baseDir)
+	changeSet, err := getChangeSet(baseDir, ScopeOptions{TrunkBranches: trunkBranches, Config: cfg, SkipGenerated: true})
+	if err != nil {
+		log.Printf("提示上下文：变更检测失败（%s）: %v", baseDir, err)
+	} else {
+		pc.ChangeSet = changeSet
+	}
+
+	args := map[string]interface{}{"projectPath": baseDir}
+	if len(trunkBranches) > 0 {
+		args["trunkBranches"] = trunkBranches
+	}
+	var req mcp.CallToolRequest
+	req.Params.Name = "code_lint"
+	req.Params.Arguments = args
+	toolResult, _ := coordinator.do(ctx, "code_lint", req, handleCodeLintRequest)
+	if toolResult == nil || len(toolResult.Content) == 0 {
+		return pc, nil
+	}
+	text := ""
+	if tc, ok := toolResult.Content[0].(*mcp.TextContent); ok {
+		text = tc.Text
+	}
+	var result LintResult
+	if err := json.Unmarshal([]byte(text), &result); err != nil {
+		pc.RawResult = text
+	} else {
+		pc.Result = &result
+	}
+	return pc, nil
+}
+
+// writeScopeSection 输出变更范围与变更文件列表
+func writeScopeSection(sb *strings.Builder, pc *promptLintContext) {
+	sb.WriteString("## 变更范围\n\n")
+	if pc.ChangeSet == nil {
+		fmt.Fprintf(sb, "未能检测到 %s 的 Git 变更范围。\n\n", pc.BaseDir)
+		return
+	}
+	if scope := pc.ChangeSet.Scope(); scope != nil && scope.Explanation != "" {
+		fmt.Fprintf(sb, "%s\n\n", scope.Explanation)
+	}
+	if len(pc.ChangeSet.Files) == 0 && len(pc.ChangeSet.Deleted) == 0 {
+		sb.WriteString("没有变更的 Go 文件。\n\n")
+		return
+	}
+	sb.WriteString("变更文件：\n")
+	for _, f := range pc.ChangeSet.Files {
+		if f.OldPath != "" {
+			fmt.Fprintf(sb, "- %s %s（原 %s）\n", f.Status, f.Path, f.OldPath)
+			continue
+		}
+		fmt.Fprintf(sb, "- %s %s\n", f.Status, f.Path)
+	}
+	for _, f := range pc.ChangeSet.Deleted {
+		fmt.Fprintf(sb, "- D %s\n", f.Path)
+	}
+	sb.WriteString("\n")
+}
+
+// writeIssuesSection 输出检查问题及其源码片段，linter 非空时只保留该 linter 的问题
+func writeIssuesSection(sb *strings.Builder, pc *promptLintContext, linter string, maxIssues int) int {
+	sb.WriteString("## 当前检查问题\n\n")
+	if pc.Result == nil {
+		if pc.RawResult != "" {
+			fmt.Fprintf(sb, "```\n%s\n```\n\n", strings.TrimSpace(pc.RawResult))
+		} else {
+			sb.WriteString("code_lint 未返回结果。\n\n")
+		}
+		return 0
+	}
+
+	var issues []Issue
+	for _, issue := range pc.Result.Issues {
+		if linter == "" || issue.FromLinter == linter {
+			issues = append(issues, issue)
+		}
+	}
+	if len(issues) == 0 {
+		sb.WriteString("未发现问题。\n\n")
+		return 0
+	}
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].Pos.Filename != issues[j].Pos.Filename {
+			return issues[i].Pos.Filename < issues[j].Pos.Filename
+		}
+		return issues[i].Pos.Line < issues[j].Pos.Line
+	})
+
+	shown := issues
+	if maxIssues > 0 && len(shown) > maxIssues {
+		shown = shown[:maxIssues]
+	}
+	for i, issue := range shown {
+		path := resolveIssuePath(pc.BaseDir, issue.Pos.Filename)
+		fmt.Fprintf(sb, "### %d. %s:%d:%d [%s]\n\n%s\n\n", i+1, path, issue.Pos.Line, issue.Pos.Column, issue.FromLinter, issue.Text)
+		if excerpt := sourceExcerpt(path, issue.Pos.Line, promptExcerptContext); excerpt != "" {
+			fmt.Fprintf(sb, "```go\n%s```\n\n", excerpt)
+		}
+	}
+	if len(shown) < len(issues) {
+		fmt.Fprintf(sb, "（另有 %d 个问题未展开，修复上述问题后可重新运行 code_lint 查看）\n\n", len(issues)-len(shown))
+	}
+	return len(issues)
+}
+
+// resolveIssuePath 将 golangci-lint 输出的相对路径解析为绝对路径（相对于起点目录或其所在模块根目录）
+func resolveIssuePath(baseDir, filename string) string {
+	if filename == "" || filepath.IsAbs(filename) {
+		return filename
+	}
+	candidates := []string{filepath.Join(baseDir, filename)}
+	if root, err := getProjectRootFromFile(filepath.Join(baseDir, "go.mod")); err == nil {
+		candidates = append(candidates, filepath.Join(root, filename))
+	}
+	for _, candidate := range candidates {
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+	}
+	return filename
+}
+
+// sourceExcerpt 返回指定行前后 context 行的源码，行首带行号，问题所在行以 ">" 标记
+func sourceExcerpt(path string, line, context int) string {
+	if line <= 0 {
+		return ""
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	start, end := line-context, line+context
+	if start < 1 {
+		start = 1
+	}
+	width := len(strconv.Itoa(end))
+	var sb strings.Builder
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan() && n <= end; n++ {
+		if n < start {
+			continue
+		}
+		marker := " "
+		if n == line {
+			marker = ">"
+		}
+		fmt.Fprintf(&sb, "%s %*d | %s\n", marker, width, n, scanner.Text())
+	}
+	return sb.String()
+}
+
+// splitPromptList 解析逗号分隔的提示参数
+func splitPromptList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// userPrompt 构造单条用户消息的提示结果
+func userPrompt(description, text string) *mcp.GetPromptResult {
+	return mcp.NewGetPromptResult(description, []mcp.PromptMessage{
+		mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(text)),
+	})
+}
+
+// handleReviewMyChangesPrompt 生成审查当前变更的提示
+func handleReviewMyChangesPrompt(ctx context.Context, req mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	projectPath := req.Params.Arguments["projectPath"]
+	if projectPath == "" {
+		return nil, fmt.Errorf("缺少参数 projectPath")
+	}
+	log.Printf("生成提示 review_my_changes: %s", projectPath)
+	pc, err := collectPromptLintContext(ctx, projectPath, splitPromptList(req.Params.Arguments["trunkBranches"]))
+	if err != nil {
+		return nil, err
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "请审查项目 %s 中当前分支的变更。\n\n", pc.BaseDir)
+	writeScopeSection(&sb, pc)
+	writeIssuesSection(&sb, pc, "", promptDefaultIssues)
+	sb.WriteString(`## 审查要求
+
+1. 逐个阅读上面列出的变更文件，关注正确性、错误处理、并发安全和导出 API 的兼容性。
+2. 对每个检查问题判断是否为真实缺陷，说明原因；误报请给出理由而不是直接加 //nolint。
+3. 指出缺少测试覆盖的变更逻辑。
+4. 按严重程度（必须修复 / 建议修改 / 可选）分组输出结论，每条结论注明文件和行号。
+`)
+	return userPrompt("审查当前变更", sb.String()), nil
+}
+
+// handleFixLintIssuesPrompt 生成修复检查问题的提示
+func handleFixLintIssuesPrompt(ctx context.Context, req mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	projectPath := req.Params.Arguments["projectPath"]
+	if projectPath == "" {
+		return nil, fmt.Errorf("缺少参数 projectPath")
+	}
+	maxIssues := promptDefaultIssues
+	if v := req.Params.Arguments["maxIssues"]; v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("maxIssues 取值无效: %q", v)
+		}
+		maxIssues = n
+	}
+	linter := strings.TrimSpace(req.Params.Arguments["linter"])
+	log.Printf("生成提示 fix_lint_issues: %s（linter: %q，最多 %d 个问题）", projectPath, linter, maxIssues)
+
+	pc, err := collectPromptLintContext(ctx, projectPath, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var sb strings.Builder
+	if linter != "" {
+		fmt.Fprintf(&sb, "请修复项目 %s 变更代码中 %s 报告的问题。\n\n", pc.BaseDir, linter)
+	} else {
+		fmt.Fprintf(&sb, "请修复项目 %s 变更代码中的检查问题。\n\n", pc.BaseDir)
+	}
+	writeScopeSection(&sb, pc)
+	if writeIssuesSection(&sb, pc, linter, maxIssues) == 0 && pc.Result != nil {
+		sb.WriteString("当前没有需要修复的问题，无需修改代码。\n")
+		return userPrompt("修复检查问题", sb.String()), nil
+	}
+	sb.WriteString(`## 修复要求
+
+1. 只修改与问题相关的代码，保持原有行为和代码风格，不做无关重构。
+2. 不要通过 //nolint 或修改 golangci-lint 配置来消除问题；确属误报时说明理由后再添加带 linter 名称和原因的 //nolint。
+3. 编译错误（FromLinter 为 go build / go vet）优先修复，它们会阻止其余检查。
+4. 修复完成后调用 code_lint 重新检查，确认问题已消除且没有引入新问题。
+`)
+	return userPrompt("修复检查问题", sb.String()), nil
+}
+
+// handleExplainLinterPrompt 生成解释 linter 的提示
+func handleExplainLinterPrompt(ctx context.Context, req mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	linter := strings.TrimSpace(req.Params.Arguments["linter"])
+	if linter == "" {
+		return nil, fmt.Errorf("缺少参数 linter")
+	}
+	projectPath := req.Params.Arguments["projectPath"]
+	log.Printf("生成提示 explain_linter: %s（项目: %q）", linter, projectPath)
+
+	dir, _ := os.Getwd()
+	if projectPath != "" {
+		baseDir, err := resolveBaseDir(projectPath, nil)
+		if err != nil {
+			return nil, err
+		}
+		dir = baseDir
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "请解释 golangci-lint 的 linter `%s`：它检查什么、为什么这类问题值得修复、典型的错误写法与正确写法，以及什么情况下可以合理地忽略。\n\n", linter)
+
+	sb.WriteString("## linter 信息\n\n")
+	var state *LinterState
+	if out, err := runToolCommand(ctx, dir, "golangci-lint", "linters"); err == nil {
+		for _, l := range parseLintersOutput(out) {
+			if l.Name == linter {
+				l := l
+				state = &l
+				break
+			}
+		}
+	} else {
+		log.Printf("获取 linter 列表失败: %v", err)
+	}
+	switch {
+	case state == nil:
+		fmt.Fprintf(&sb, "未能从 golangci-lint linters 获取 %s 的信息（可能名称有误或 golangci-lint 不可用）。\n\n", linter)
+	case state.Enabled:
+		fmt.Fprintf(&sb, "- 说明：%s\n- 在 %s 的配置中：已启用\n\n", state.Description, dir)
+	default:
+		fmt.Fprintf(&sb, "- 说明：%s\n- 在 %s 的配置中：未启用\n\n", state.Description, dir)
+	}
+
+	if projectPath != "" {
+		pc, err := collectPromptLintContext(ctx, projectPath, nil)
+		if err != nil {
+			return nil, err
+		}
+		if n := writeIssuesSection(&sb, pc, linter, promptDefaultIssues); n > 0 {
+			sb.WriteString("请结合上面的实际问题逐条说明触发原因和修改方法。\n")
+		}
+	}
+	return userPrompt("解释 linter "+linter, sb.String()), nil
+}
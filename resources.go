@@ -0,0 +1,320 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// 资源 URI。{+path} 为项目目录的绝对路径（允许包含 /，也可整体 URL 编码）
+const (
+	scopeResourceTemplate  = "lint://project/{+path}/scope"
+	configResourceTemplate = "lint://project/{+path}/config"
+	environmentResourceURI = "lint://environment"
+)
+
+// linterReports 缓存每个项目最近一次 golangci-lint 报告中的 linter 启用情况，供 config 资源直接读取
+var (
+	linterReportsMu sync.Mutex
+	linterReports   = make(map[string][]LinterState)
+)
+
+// recordLinterReport 记录一次 golangci-lint 运行报告中的 linter 列表
+func recordLinterReport(projectRoot string, linters []LinterState) {
+	if len(linters) == 0 {
+		return
+	}
+	linterReportsMu.Lock()
+	defer linterReportsMu.Unlock()
+	linterReports[filepath.Clean(projectRoot)] = append([]LinterState(nil), linters...)
+}
+
+// cachedLinterReport 返回项目最近一次检查记录的 linter 列表
+func cachedLinterReport(projectRoot string) ([]LinterState, bool) {
+	linterReportsMu.Lock()
+	defer linterReportsMu.Unlock()
+	linters, ok := linterReports[filepath.Clean(projectRoot)]
+	return linters, ok
+}
+
+// ProjectScopeResource lint://project/{path}/scope 的内容：当前变更范围及涉及的文件、包和模块
+type ProjectScopeResource struct {
+	Scope            *ScopeInfo          `json:"Scope,omitempty"`
+	Files            []ChangedFile       `json:"Files"`
+	Deleted          []ChangedFile       `json:"Deleted,omitempty"`
+	SkippedGenerated []string            `json:"SkippedGenerated,omitempty"`
+	Packages         map[string][]string `json:"Packages"` // 模块根目录 -> 包路径（含有文件被删除的包）
+	Modules          []string            `json:"Modules"`
+	Error            string              `json:"Error,omitempty"`
+}
+
+// ProjectConfigResource lint://project/{path}/config 的内容：golangci-lint 生效配置与 linter 启用情况
+type ProjectConfigResource struct {
+	ProjectRoot    string           `json:"ProjectRoot"`
+	GolangciConfig string           `json:"GolangciConfig,omitempty"` // 生效的配置文件路径，为空表示使用默认配置
+	ConfigContent  string           `json:"ConfigContent,omitempty"`
+	Linters        []LinterState    `json:"Linters"`
+	LintersSource  string           `json:"LintersSource"` // "last-run"（最近一次检查报告）或 "golangci-lint linters"
+	LintMCPConfig  *EffectiveConfig `json:"LintMCPConfig"`
+	Error          string           `json:"Error,omitempty"`
+}
+
+// EnvironmentResource lint://environment 的内容
+type EnvironmentResource struct {
+	GoVersion       string `json:"GoVersion"`
+	GOOS            string `json:"GOOS"`
+	GOARCH          string `json:"GOARCH"`
+	GolangciVersion string `json:"GolangciVersion"`
+	ServerVersion   string `json:"ServerVersion"`
+	// Roots 客户端 roots（未提供时为工作区白名单）及其中的 Go 模块；两者都未配置时为空
+	Roots []EnvironmentRoot `json:"Roots"`
+}
+
+// EnvironmentRoot 单个工作区根目录的环境信息
+type EnvironmentRoot struct {
+	Root    string              `json:"Root"`
+	Source  string              `json:"Source"`
+	Modules []EnvironmentModule `json:"Modules"` // root 所在的模块，或 root 下发现的模块
+}
+
+// EnvironmentModule Go 模块的 vendor 模式（按模块根目录的 .gitignore 判断）
+type EnvironmentModule struct {
+	Root       string `json:"Root"`
+	VendorMode bool   `json:"VendorMode"`
+}
+
+// registerResources 注册只读资源，代理可在决定是否检查前低成本地读取项目状态
+func registerResources(s *server.MCPServer) {
+	s.AddResourceTemplate(mcp.NewResourceTemplate(scopeResourceTemplate, "project-scope",
+		mcp.WithTemplateDescription("项目当前的变更范围：基准提交及选择依据、变更/删除的文件、涉及的包和模块（与 code_lint 默认检查范围一致）"),
+		mcp.WithTemplateMIMEType("application/json"),
+	), handleScopeResource)
+
+	s.AddResourceTemplate(mcp.NewResourceTemplate(configResourceTemplate, "project-config",
+		mcp.WithTemplateDescription("项目生效的 golangci-lint 配置文件及启用的 linter，以及合并后的 .lint-mcp.yaml 配置"),
+		mcp.WithTemplateMIMEType("application/json"),
+	), handleConfigResource)
+
+	s.AddResource(mcp.NewResource(environmentResourceURI, "environment",
+		mcp.WithResourceDescription("运行环境：Go 版本、golangci-lint 版本，以及客户端 roots（或工作区白名单）中各 Go 模块的 vendor 模式"),
+		mcp.WithMIMEType("application/json"),
+	), handleEnvironmentResource)
+}
+
+// resourcePathArg 从资源模板参数中取出项目路径
+func resourcePathArg(req mcp.ReadResourceRequest) (string, error) {
+	var raw string
+	switch v := req.Params.Arguments["path"].(type) {
+	case []string:
+		raw = strings.Join(v, "/")
+	case string:
+		raw = v
+	}
+	if decoded, err := url.PathUnescape(raw); err == nil {
+		raw = decoded
+	}
+	if raw == "" {
+		return "", fmt.Errorf("资源 URI 缺少项目路径: %s", req.Params.URI)
+	}
+	if !strings.HasPrefix(raw, "/") && !filepath.IsAbs(raw) {
+		raw = "/" + raw
+	}
+	return resolveBaseDir(raw, nil)
+}
+
+// jsonResourceContents 将结果序列化为 JSON 资源内容
+func jsonResourceContents(uri string, v interface{}) ([]mcp.ResourceContents, error) {
+	data, err := json.MarshalIndent(v, "", " ")
+	if err != nil {
+		return nil, fmt.Errorf("序列化资源失败: %v", err)
+	}
+	return []mcp.ResourceContents{mcp.TextResourceContents{URI: uri, MIMEType: "application/json", Text: string(data)}}, nil
+}
+
+// handleScopeResource 读取项目当前的变更范围
+func handleScopeResource(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	baseDir, err := resourcePathArg(req)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("读取变更范围资源: %s", baseDir)
+
+	cfg := loadEffectiveConfig(baseDir)
+	result := &ProjectScopeResource{Files: []ChangedFile{}, Packages: map[string][]string{}, Modules: []string{}}
+	changeSet, err := getChangeSet(baseDir, ScopeOptions{Config: cfg, SkipGenerated: true})
+	if err != nil {
+		result.Error = err.Error()
+		return jsonResourceContents(req.Params.URI, result)
+	}
+
+	result.Scope = changeSet.Scope()
+	result.Files = append(result.Files, changeSet.Files...)
+	result.Deleted = changeSet.Deleted
+	result.SkippedGenerated = changeSet.SkippedGenerated
+	if paths := changeSet.Paths(); len(paths) > 0 {
+		if pkgs, err := getPackagesFromFiles(paths); err == nil {
+			result.Packages = pkgs
+		}
+	}
+	mergeProjectPackages(result.Packages, changeSet.DeletedPackages())
+	for module, pkgs := range result.Packages {
+		sort.Strings(pkgs)
+		result.Modules = append(result.Modules, module)
+	}
+	sort.Strings(result.Modules)
+	return jsonResourceContents(req.Params.URI, result)
+}
+
+// handleConfigResource 读取项目生效的 golangci-lint 配置与 linter 启用情况
+func handleConfigResource(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	baseDir, err := resourcePathArg(req)
+	if err != nil {
+		return nil, err
+	}
+	projectRoot := baseDir
+	if root, err := getProjectRootFromFile(filepath.Join(baseDir, "go.mod")); err == nil {
+		projectRoot = root
+	}
+	log.Printf("读取配置资源: %s（模块根目录: %s）", baseDir, projectRoot)
+
+	result := &ProjectConfigResource{ProjectRoot: projectRoot, Linters: []LinterState{}, LintMCPConfig: loadEffectiveConfig(baseDir)}
+	if err := checkGolangciLintInstalled(); err != nil {
+		result.Error = err.Error()
+		return jsonResourceContents(req.Params.URI, result)
+	}
+
+	// golangci-lint config path 在未找到配置文件时返回非零退出码，此时使用默认配置
+	if out, err := runToolCommand(ctx, projectRoot, "golangci-lint", "config", "path"); err == nil {
+		result.GolangciConfig = strings.TrimSpace(out)
+		if result.GolangciConfig != "" && !filepath.IsAbs(result.GolangciConfig) {
+			result.GolangciConfig = filepath.Join(projectRoot, result.GolangciConfig)
+		}
+		if content, err := os.ReadFile(result.GolangciConfig); err == nil {
+			result.ConfigContent = string(content)
+		}
+	}
+
+	if linters, ok := cachedLinterReport(projectRoot); ok {
+		result.Linters = linters
+		result.LintersSource = "last-run"
+		return jsonResourceContents(req.Params.URI, result)
+	}
+	out, err := runToolCommand(ctx, projectRoot, "golangci-lint", "linters")
+	if err != nil {
+		result.Error = fmt.Sprintf("获取 linter 列表失败: %v", err)
+		return jsonResourceContents(req.Params.URI, result)
+	}
+	result.Linters = parseLintersOutput(out)
+	result.LintersSource = "golangci-lint linters"
+	return jsonResourceContents(req.Params.URI, result)
+}
+
+// parseLintersOutput 解析 golangci-lint linters 的文本输出，
+// 格式为 "Enabled by your configuration linters:" / "Disabled by your configuration linters:" 两段，每行 "name: 描述"
+func parseLintersOutput(output string) []LinterState {
+	var linters []LinterState
+	enabled := false
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "Enabled by"):
+			enabled = true
+			continue
+		case strings.HasPrefix(line, "Disabled by"):
+			enabled = false
+			continue
+		}
+		name, desc, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		// 废弃的 linter 形如 "golint [deprecated]: ..."，带别名的形如 "govet (vet, vetshadow): ..."
+		if idx := strings.IndexAny(name, " ("); idx > 0 {
+			name = name[:idx]
+		}
+		// 描述末尾的 "[fast: false, auto-fix: false]" 不属于说明文字
+		if idx := strings.LastIndex(desc, " [fast:"); idx >= 0 {
+			desc = desc[:idx]
+		}
+		linters = append(linters, LinterState{Name: name, Enabled: enabled, Description: strings.TrimSpace(desc)})
+	}
+	return linters
+}
+
+// handleEnvironmentResource 读取运行环境信息
+func handleEnvironmentResource(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	workDir, _ := os.Getwd()
+	env := &EnvironmentResource{
+		GOOS:          runtime.GOOS,
+		GOARCH:        runtime.GOARCH,
+		ServerVersion: serverVersion,
+		Roots:         environmentRoots(),
+	}
+	if out, err := runToolCommand(ctx, workDir, "go", "env", "GOVERSION"); err == nil {
+		env.GoVersion = strings.TrimSpace(out)
+	} else {
+		env.GoVersion = fmt.Sprintf("不可用: %v", err)
+	}
+	if out, err := runToolCommand(ctx, workDir, "golangci-lint", "--version"); err == nil {
+		env.GolangciVersion = strings.TrimSpace(out)
+	} else {
+		env.GolangciVersion = fmt.Sprintf("不可用: %v", err)
+	}
+	return jsonResourceContents(req.Params.URI, env)
+}
+
+// environmentRoots 按客户端 roots（未提供时为工作区白名单）列出各根目录中的 Go 模块及其 vendor 模式。
+// 服务进程的工作目录与客户端项目无关，不作为依据
+func environmentRoots() []EnvironmentRoot {
+	roots, source := clientRoots.Paths(), "客户端 roots/list"
+	if len(roots) == 0 {
+		roots, source = allowedRoots.List()
+	}
+	result := make([]EnvironmentRoot, 0, len(roots))
+	for _, root := range roots {
+		entry := EnvironmentRoot{Root: root, Source: source, Modules: []EnvironmentModule{}}
+		moduleRoots := []string{}
+		if modRoot, err := findGoModRoot(root); err == nil {
+			moduleRoots = append(moduleRoots, modRoot)
+		} else {
+			for _, project := range discoverProjects(root) {
+				if _, err := os.Stat(filepath.Join(project, "go.mod")); err == nil {
+					moduleRoots = append(moduleRoots, project)
+				}
+			}
+		}
+		for _, modRoot := range moduleRoots {
+			entry.Modules = append(entry.Modules, EnvironmentModule{Root: modRoot, VendorMode: autoDetectVendorMode(modRoot)})
+		}
+		result = append(result, entry)
+	}
+	return result
+}
+
+// runToolCommand 在指定目录执行外部命令并返回标准输出
+func runToolCommand(ctx context.Context, dir, name string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	cmd.Env = os.Environ()
+	out, err := cmd.Output()
+	if err != nil {
+		return string(out), fmt.Errorf("%s %s: %v", name, strings.Join(args, " "), err)
+	}
+	return string(out), nil
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// rootsRequestTimeout 等待客户端响应 roots/list 的最长时间
+const rootsRequestTimeout = 5 * time.Second
+
+// clientRootsState 客户端通过 roots/list 提供的工作区根目录
+type clientRootsState struct {
+	mu        sync.RWMutex
+	supported bool          // 客户端在 initialize 中声明了 roots 能力
+	roots     []string      // 本地目录（已从 file:// URI 转换）
+	ready     chan struct{} // 首次获取完成（成功或失败）后关闭
+	readyOnce *sync.Once
+}
+
+// clientRoots 全局客户端根目录状态
+var clientRoots = &clientRootsState{ready: make(chan struct{}), readyOnce: &sync.Once{}}
+
+// registerClientRoots 在 initialize 时记录客户端是否支持 roots，初始化完成及 roots 变更时重新获取
+func registerClientRoots(s *server.MCPServer, hooks *server.Hooks) {
+	hooks.AddBeforeInitialize(func(id any, req *mcp.InitializeRequest) {
+		supported := req.Params.Capabilities.Roots != nil
+		clientRoots.mu.Lock()
+		clientRoots.supported = supported
+		clientRoots.mu.Unlock()
+		if !supported {
+			log.Printf("客户端未声明 roots 能力，未指定 projectPath/files 时无法确定默认项目位置")
+			clientRoots.markReady()
+		}
+	})
+	s.AddNotificationHandler("notifications/initialized", func(ctx context.Context, n mcp.JSONRPCNotification) {
+		go clientRoots.refresh()
+	})
+	s.AddNotificationHandler("notifications/roots/list_changed", func(ctx context.Context, n mcp.JSONRPCNotification) {
+		log.Printf("客户端 roots 已变更，重新获取")
+		clientRoots.invalidate()
+		go clientRoots.refresh()
+	})
+}
+
+func (c *clientRootsState) markReady() {
+	c.mu.RLock()
+	once, ready := c.readyOnce, c.ready
+	c.mu.RUnlock()
+	once.Do(func() { close(ready) })
+}
+
+// invalidate roots 变更后，重新获取完成前的请求等待新的 roots
+func (c *clientRootsState) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	select {
+	case <-c.ready:
+		c.ready = make(chan struct{})
+		c.readyOnce = &sync.Once{}
+	default:
+	}
+}
+
+// refresh 向客户端请求 roots/list 并更新根目录
+func (c *clientRootsState) refresh() {
+	defer c.markReady()
+
+	c.mu.RLock()
+	supported := c.supported
+	c.mu.RUnlock()
+	if !supported || transport == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), rootsRequestTimeout)
+	defer cancel()
+	raw, err := transport.request(ctx, "roots/list", nil)
+	if err != nil {
+		log.Printf("获取客户端 roots 失败: %v", err)
+		return
+	}
+	var result mcp.ListRootsResult
+	if err := json.Unmarshal(raw, &result); err != nil {
+		log.Printf("解析 roots/list 响应失败: %v", err)
+		return
+	}
+
+	var paths []string
+	for _, root := range result.Roots {
+		path, ok := rootURIToPath(root.URI)
+		if !ok {
+			log.Printf("忽略不支持的 root: %s", root.URI)
+			continue
+		}
+		if stat, err := os.Stat(path); err != nil || !stat.IsDir() {
+			log.Printf("忽略不存在或不是目录的 root: %s", path)
+			continue
+		}
+		paths = append(paths, path)
+	}
+	paths = dedupeStrings(paths)
+	log.Printf("客户端 roots: %v", paths)
+
+	c.mu.Lock()
+	c.roots = paths
+	c.mu.Unlock()
+	allowedRoots.SetClientRoots(paths)
+	if live.isEnabled() {
+		go func() { live.sync(defaultLintTargets(paths)) }()
+	}
+}
+
+// waitReady 客户端支持 roots 时等待首次获取完成（最多 rootsRequestTimeout），
+// 避免初始化后立即到达的请求绕过由客户端 roots 构成的白名单
+func (c *clientRootsState) waitReady() {
+	c.mu.RLock()
+	supported := c.supported
+	ready := c.ready
+	c.mu.RUnlock()
+	if !supported {
+		return
+	}
+	select {
+	case <-ready:
+	case <-time.After(rootsRequestTimeout):
+		log.Printf("等待客户端 roots 超时")
+	}
+}
+
+// Paths 返回客户端根目录
+func (c *clientRootsState) Paths() []string {
+	c.waitReady()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]string(nil), c.roots...)
+}
+
+// rootURIToPath 将 file:// URI 转换为本地路径
+func rootURIToPath(uri string) (string, bool) {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return "", false
+	}
+	path := u.Path
+	// Windows 的 file:///C:/work 解析后为 /C:/work
+	if runtime.GOOS == "windows" && len(path) > 2 && path[0] == '/' && path[2] == ':' {
+		path = path[1:]
+	}
+	path = filepath.Clean(filepath.FromSlash(path))
+	if !filepath.IsAbs(path) {
+		return "", false
+	}
+	return path, true
+}
+
+// defaultLintTargets 未指定 projectPath/files 时，从客户端 roots 中发现待检查的目录：
+// 位于 Git 仓库内的 root 作为一个整体检查（变更检测会覆盖其中的多个模块和嵌套仓库），
+// 否则在 root 下查找 Git 仓库与 Go 模块，各自作为一个检查目标
+func defaultLintTargets(roots []string) []string {
+	var targets []string
+	for _, root := range roots {
+		if allowedRoots.Check(root) != nil {
+			log.Printf("客户端 root 不在工作区白名单内，跳过: %s", root)
+			continue
+		}
+		if _, err := newGitRepo(root).TopLevel(); err == nil {
+			targets = append(targets, root)
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(root, "go.mod")); err == nil {
+			targets = append(targets, root)
+			continue
+		}
+		targets = append(targets, discoverProjects(root)...)
+	}
+	targets = dedupeStrings(targets)
+	sort.Strings(targets)
+	return targets
+}
+
+// discoverProjects 在目录下查找 Git 仓库根目录与 Go 模块根目录（找到后不再深入），
+// 跳过 vendor、node_modules、testdata 与隐藏目录
+func discoverProjects(root string) []string {
+	var projects []string
+	_ = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.IsDir() {
+			return nil
+		}
+		if path != root && isSkippedWalkDir(info.Name()) {
+			return filepath.SkipDir
+		}
+		_, gitErr := os.Stat(filepath.Join(path, ".git"))
+		_, modErr := os.Stat(filepath.Join(path, "go.mod"))
+		if gitErr == nil || modErr == nil {
+			projects = append(projects, path)
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	log.Printf("在 %s 下发现 %d 个项目: %v", root, len(projects), projects)
+	return projects
+}
+
+// isSkippedWalkDir 判断遍历目录时是否跳过：vendor、node_modules、testdata 与隐藏目录（含 .git）
+func isSkippedWalkDir(name string) bool {
+	return name == "vendor" || name == "node_modules" || name == "testdata" || strings.HasPrefix(name, ".")
+}
+
+// jsonResultKey 标记内部汇总多个检查结果的子请求：结果统一使用 JSON（忽略 outputFormat）以便合并
+type jsonResultKey struct{}
+
+// outputConfig 返回用于输出结果的配置
+func outputConfig(ctx context.Context, cfg *EffectiveConfig) *EffectiveConfig {
+	if ctx.Value(jsonResultKey{}) == nil || cfg == nil || cfg.OutputFormat == outputFormatJSON {
+		return cfg
+	}
+	jsonCfg := *cfg
+	jsonCfg.OutputFormat = outputFormatJSON
+	return &jsonCfg
+}
+
+// startPathArgs 从请求参数中取出 projectPath 与 files
+func startPathArgs(args map[string]interface{}) (string, []string) {
+	projectPath, _ := args["projectPath"].(string)
+	var files []string
+	if list, ok := args["files"].([]interface{}); ok {
+		for _, f := range list {
+			if s, ok := f.(string); ok {
+				files = append(files, s)
+			}
+		}
+	}
+	return projectPath, files
+}
+
+// hasStartPath 请求参数中是否指定了 projectPath 或 files
+func hasStartPath(args map[string]interface{}) bool {
+	if p, ok := args["projectPath"].(string); ok && strings.TrimSpace(p) != "" {
+		return true
+	}
+	files, ok := args["files"].([]interface{})
+	return ok && len(files) > 0
+}
+
+// lintDefaultTargets 依次检查从客户端 roots 发现的每个目标并合并结果
+func lintDefaultTargets(ctx context.Context, req mcp.CallToolRequest, targets []string) *mcp.CallToolResult {
+	log.Printf("未指定 projectPath/files，按客户端 roots 检查 %d 个目标: %v", len(targets), targets)
+	merged := &LintResult{Issues: []Issue{}, Passed: true}
+	subCtx := context.WithValue(ctx, jsonResultKey{}, true)
+	seen := make(map[string]bool)
+	duplicates := 0
+	for _, target := range targets {
+		args := make(map[string]interface{}, len(req.Params.Arguments)+1)
+		for k, v := range req.Params.Arguments {
+			args[k] = v
+		}
+		args["projectPath"] = target
+		sub := req
+		sub.Params.Arguments = args
+
+		summary := LintTargetSummary{Path: target}
+		toolResult, _ := coordinator.do(subCtx, "code_lint", sub, handleCodeLintRequest)
+		var result LintResult
+		if toolResult != nil && len(toolResult.Content) > 0 {
+			if tc, ok := toolResult.Content[0].(*mcp.TextContent); ok {
+				if err := json.Unmarshal([]byte(tc.Text), &result); err != nil {
+					log.Printf("解析目标 %s 的检查结果失败: %v", target, err)
+				}
+			}
+		}
+		summary.Issues = len(result.Issues)
+		summary.Scope = result.Scope
+		// 各目标的结果已去重；目标重叠（如嵌套的 roots）时只去除与之前目标重复的问题，
+		// 同一目标内不同模块中同名文件的问题在 JSON 结果中无法区分，不能再次合并
+		var targetKeys []string
+		for _, issue := range result.Issues {
+			key := dedupeKey(issue)
+			if seen[key] {
+				duplicates++
+				continue
+			}
+			targetKeys = append(targetKeys, key)
+			merged.Issues = append(merged.Issues, issue)
+		}
+		for _, key := range targetKeys {
+			seen[key] = true
+		}
+		merged.Dependents = append(merged.Dependents, result.Dependents...)
+		merged.SkippedGenerated += result.SkippedGenerated
+		merged.Diff = mergeRunDiffs(merged.Diff, result.Diff)
+		merged.Passed = merged.Passed && result.Passed
+		merged.Partial = merged.Partial || result.Partial
+		merged.Targets = append(merged.Targets, summary)
+	}
+	if duplicates > 0 {
+		log.Printf("去除 %d 个与其他目标重复的问题", duplicates)
+	}
+	return buildToolResult(merged, loadEffectiveConfig(targets[0]))
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+)
+
+// 问题严重程度，由高到低
+const (
+	severityError   = "error"
+	severityWarning = "warning"
+	severityInfo    = "info"
+)
+
+// severityRanks 严重程度的高低，数值越大越严重
+var severityRanks = map[string]int{severityInfo: 1, severityWarning: 2, severityError: 3}
+
+// SeverityConfig 问题严重程度映射与通过标准（.lint-mcp.yaml 的 severity 配置项）
+type SeverityConfig struct {
+	// Default 未匹配任何规则、且检查工具未给出严重程度时使用，默认 warning
+	Default string `yaml:"default" json:"default,omitempty"`
+	// Rules 按顺序匹配，第一条匹配的规则生效；整体替换低优先级配置中的规则
+	Rules []SeverityRule `yaml:"rules" json:"rules,omitempty"`
+	// FailOn 存在该严重程度及以上的问题时检查不通过（Passed=false），默认 warning
+	FailOn string `yaml:"failOn" json:"failOn,omitempty"`
+}
+
+// SeverityRule 严重程度映射规则，linter 与 text 均为空的规则匹配所有问题
+type SeverityRule struct {
+	// Linter 问题的 FromLinter，为空匹配所有 linter
+	Linter string `yaml:"linter" json:"linter,omitempty"`
+	// Text 匹配问题描述的正则表达式，为空匹配所有描述
+	Text     string `yaml:"text" json:"text,omitempty"`
+	Severity string `yaml:"severity" json:"severity"`
+}
+
+// builtinSeverityRules 在配置的规则之后匹配：类型检查错误意味着代码无法编译
+var builtinSeverityRules = []SeverityRule{{Linter: "typecheck", Severity: severityError}}
+
+// lockedSeverityRules 在配置的规则之前匹配且不可覆盖：工具自身执行失败时检查并未完成，Passed 不能为 true
+var lockedSeverityRules = []SeverityRule{
+	{Linter: "lint-mcp", Severity: severityError},
+	{Linter: "golangci-lint", Severity: severityError},
+}
+
+// isToolFailure 判断问题是否表示工具自身执行失败（而非代码问题）
+func isToolFailure(issue Issue) bool {
+	for _, rule := range lockedSeverityRules {
+		if issue.FromLinter == rule.Linter {
+			return true
+		}
+	}
+	return false
+}
+
+// validSeverity 判断严重程度取值是否有效
+func validSeverity(s string) bool {
+	_, ok := severityRanks[s]
+	return ok
+}
+
+// validate 检查严重程度配置取值
+func (c *SeverityConfig) validate() error {
+	if c == nil {
+		return nil
+	}
+	for name, value := range map[string]string{"default": c.Default, "failOn": c.FailOn} {
+		if value != "" && !validSeverity(value) {
+			return fmt.Errorf("severity.%s 只支持 error、warning 或 info，实际为 %q", name, value)
+		}
+	}
+	for i, rule := range c.Rules {
+		if !validSeverity(rule.Severity) {
+			return fmt.Errorf("severity.rules[%d].severity 只支持 error、warning 或 info，实际为 %q", i, rule.Severity)
+		}
+		if _, err := regexp.Compile(rule.Text); err != nil {
+			return fmt.Errorf("severity.rules[%d].text 不是有效的正则表达式: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// merge 按字段覆盖，返回被覆盖的字段名
+func (c *SeverityConfig) merge(other *SeverityConfig) []string {
+	var fields []string
+	if other.Default != "" {
+		c.Default = other.Default
+		fields = append(fields, "default")
+	}
+	if other.Rules != nil {
+		c.Rules = other.Rules
+		fields = append(fields, "rules")
+	}
+	if other.FailOn != "" {
+		c.FailOn = other.FailOn
+		fields = append(fields, "failOn")
+	}
+	return fields
+}
+
+// severityMatcher 已编译的映射规则
+type severityMatcher struct {
+	rule SeverityRule
+	text *regexp.Regexp
+}
+
+// severityOf 确定问题的严重程度：不可覆盖的内置规则 > 配置的规则 > 内置规则 > 检查工具给出的严重程度 > 默认值
+func severityOf(issue Issue, matchers []severityMatcher, defaultSeverity string) string {
+	for _, m := range matchers {
+		if m.rule.Linter != "" && m.rule.Linter != issue.FromLinter {
+			continue
+		}
+		if m.text != nil && !m.text.MatchString(issue.Text) {
+			continue
+		}
+		return m.rule.Severity
+	}
+	if s := strings.ToLower(issue.Severity); validSeverity(s) {
+		return s
+	}
+	return defaultSeverity
+}
+
+// applySeverityPolicy 按配置为问题设置严重程度，并按 failOn 判断检查是否通过
+func applySeverityPolicy(cfg *EffectiveConfig, result *LintResult) {
+	policy := &SeverityConfig{}
+	if cfg != nil && cfg.Severity != nil {
+		policy = cfg.Severity
+	}
+	defaultSeverity, failOn := policy.Default, policy.FailOn
+	if defaultSeverity == "" {
+		defaultSeverity = severityWarning
+	}
+	if failOn == "" {
+		failOn = severityWarning
+	}
+
+	var matchers []severityMatcher
+	rules := append(append([]SeverityRule(nil), lockedSeverityRules...), policy.Rules...)
+	for _, rule := range append(rules, builtinSeverityRules...) {
+		m := severityMatcher{rule: rule}
+		if rule.Text != "" {
+			re, err := regexp.Compile(rule.Text)
+			if err != nil {
+				log.Printf("忽略无效的严重程度规则 %q: %v", rule.Text, err)
+				continue
+			}
+			m.text = re
+		}
+		matchers = append(matchers, m)
+	}
+
+	result.Passed = true
+	failing := 0
+	for i := range result.Issues {
+		result.Issues[i].Severity = severityOf(result.Issues[i], matchers, defaultSeverity)
+		if severityRanks[result.Issues[i].Severity] >= severityRanks[failOn] {
+			failing++
+		}
+	}
+	if failing > 0 {
+		result.Passed = false
+		log.Printf("%d 个问题达到 %s 及以上级别，检查未通过", failing, failOn)
+	}
+}
@@ -0,0 +1,350 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// stdioSession 标准输入输出上唯一的客户端会话
+type stdioSession struct {
+	notifications chan mcp.JSONRPCNotification
+	initialized   atomic.Bool
+}
+
+func (s *stdioSession) SessionID() string { return "stdio" }
+
+func (s *stdioSession) NotificationChannel() chan<- mcp.JSONRPCNotification {
+	return s.notifications
+}
+
+func (s *stdioSession) Initialize() { s.initialized.Store(true) }
+
+func (s *stdioSession) Initialized() bool { return s.initialized.Load() }
+
+// rpcReply 客户端对服务端请求的响应
+type rpcReply struct {
+	Result json.RawMessage
+	Err    error
+}
+
+// stdioTransport 基于标准输入输出的 JSON-RPC 传输。
+// mcp-go 自带的 ServeStdio 只能被动应答，无法向客户端发起请求（如 roots/list），
+// 这里自行读写消息：客户端请求交给 MCPServer 并发处理，客户端的响应按 id 分发给等待中的服务端请求
+type stdioTransport struct {
+	server  *server.MCPServer
+	session *stdioSession
+	out     io.Writer
+	writeMu sync.Mutex
+
+	nextID    atomic.Int64
+	pendingMu sync.Mutex
+	pending   map[string]chan rpcReply
+
+	subscriptionsMu sync.Mutex
+	subscriptions   map[string]bool // 客户端通过 resources/subscribe 订阅的资源 URI
+
+	requestsMu sync.Mutex
+	requests   map[string]context.CancelFunc // 处理中的客户端请求 id -> 取消函数
+}
+
+// transport 当前的 stdio 传输，供需要向客户端发起请求的模块使用
+var transport *stdioTransport
+
+func newStdioTransport(s *server.MCPServer, out io.Writer) *stdioTransport {
+	return &stdioTransport{
+		server:  s,
+		session: &stdioSession{notifications: make(chan mcp.JSONRPCNotification, 100)},
+		out:     out,
+		pending: make(map[string]chan rpcReply),
+
+		subscriptions: make(map[string]bool),
+		requests:      make(map[string]context.CancelFunc),
+	}
+}
+
+// serveStdio 在标准输入输出上运行服务，收到 SIGTERM/SIGINT 时退出
+func serveStdio(s *server.MCPServer) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGTERM, syscall.SIGINT)
+	go func() {
+		<-sigChan
+		cancel()
+	}()
+
+	transport = newStdioTransport(s, os.Stdout)
+	return transport.listen(ctx, os.Stdin)
+}
+
+// listen 读取并分发消息，直到输入结束或 ctx 取消
+func (t *stdioTransport) listen(ctx context.Context, in io.Reader) error {
+	if err := t.server.RegisterSession(t.session); err != nil {
+		return fmt.Errorf("注册会话失败: %w", err)
+	}
+	defer t.server.UnregisterSession(t.session.SessionID())
+	ctx = t.server.WithContext(ctx, t.session)
+
+	go func() {
+		for {
+			select {
+			case notification := <-t.session.notifications:
+				if err := t.write(notification); err != nil {
+					log.Printf("发送通知失败: %v", err)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	lines := make(chan string)
+	readErr := make(chan error, 1)
+	go func() {
+		reader := bufio.NewReader(in)
+		for {
+			line, err := reader.ReadString('\n')
+			if len(line) > 0 {
+				lines <- line
+			}
+			if err != nil {
+				readErr <- err
+				return
+			}
+		}
+	}()
+
+	var handlers sync.WaitGroup
+	defer handlers.Wait()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-readErr:
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("读取输入失败: %w", err)
+		case line := <-lines:
+			t.dispatch(ctx, line, &handlers)
+		}
+	}
+}
+
+// dispatch 处理一条消息：响应交给等待方；通知与 initialize 同步处理以保持顺序；其余请求并发处理，
+// 避免耗时的检查阻塞后续消息（包括检查过程中依赖的 roots/list 响应）
+func (t *stdioTransport) dispatch(ctx context.Context, line string, handlers *sync.WaitGroup) {
+	var envelope struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+		Params json.RawMessage `json:"params"`
+		Result json.RawMessage `json:"result"`
+		Error  *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	raw := json.RawMessage(line)
+	if err := json.Unmarshal(raw, &envelope); err != nil {
+		_ = t.write(map[string]interface{}{
+			"jsonrpc": mcp.JSONRPC_VERSION,
+			"id":      nil,
+			"error":   map[string]interface{}{"code": mcp.PARSE_ERROR, "message": "Parse error"},
+		})
+		return
+	}
+
+	if envelope.Method == "" && len(envelope.ID) > 0 {
+		reply := rpcReply{Result: envelope.Result}
+		if envelope.Error != nil {
+			reply.Err = fmt.Errorf("客户端返回错误 %d: %s", envelope.Error.Code, envelope.Error.Message)
+		}
+		t.resolve(string(envelope.ID), reply)
+		return
+	}
+
+	if len(envelope.ID) == 0 || string(envelope.ID) == "null" {
+		if envelope.Method == "notifications/cancelled" {
+			t.cancelRequest(envelope.Params)
+		}
+		t.server.HandleMessage(ctx, raw)
+		return
+	}
+
+	// mcp-go 未实现资源订阅，由传输层记录订阅并在资源变化时通知
+	if envelope.Method == "resources/subscribe" || envelope.Method == "resources/unsubscribe" {
+		t.handleSubscription(envelope.ID, envelope.Method, envelope.Params)
+		return
+	}
+
+	// initialize 必须在其他请求之前完成（记录客户端能力）
+	if envelope.Method == string(mcp.MethodInitialize) {
+		if response := t.server.HandleMessage(ctx, raw); response != nil {
+			if err := t.write(response); err != nil {
+				log.Printf("发送响应失败: %v", err)
+			}
+		}
+		return
+	}
+
+	// 每个请求使用独立的 ctx，客户端发送 notifications/cancelled（如超时）时取消
+	id := string(envelope.ID)
+	reqCtx, cancel := context.WithCancel(ctx)
+	t.requestsMu.Lock()
+	t.requests[id] = cancel
+	t.requestsMu.Unlock()
+
+	handlers.Add(1)
+	go func() {
+		defer handlers.Done()
+		defer func() {
+			t.requestsMu.Lock()
+			delete(t.requests, id)
+			t.requestsMu.Unlock()
+			cancel()
+		}()
+		response := t.server.HandleMessage(reqCtx, raw)
+		if reqCtx.Err() != nil && ctx.Err() == nil {
+			// 客户端已取消的请求不再发送响应
+			log.Printf("请求 %s 已被客户端取消，不发送响应", id)
+			return
+		}
+		if response != nil {
+			if err := t.write(response); err != nil {
+				log.Printf("发送响应失败: %v", err)
+			}
+		}
+	}()
+}
+
+// cancelRequest 处理 notifications/cancelled：取消对应请求的 ctx，排队或执行中的检查随之结束
+func (t *stdioTransport) cancelRequest(params json.RawMessage) {
+	var p struct {
+		RequestID json.RawMessage `json:"requestId"`
+		Reason    string          `json:"reason"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil || len(p.RequestID) == 0 {
+		return
+	}
+	t.requestsMu.Lock()
+	cancel, ok := t.requests[string(p.RequestID)]
+	t.requestsMu.Unlock()
+	if ok {
+		log.Printf("客户端取消请求 %s: %s", p.RequestID, p.Reason)
+		cancel()
+	}
+}
+
+// write 写出一条消息（一行 JSON）
+func (t *stdioTransport) write(message interface{}) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	_, err = fmt.Fprintf(t.out, "%s\n", data)
+	return err
+}
+
+// request 向客户端发起请求并等待响应
+func (t *stdioTransport) request(ctx context.Context, method string, params interface{}) (json.RawMessage, error) {
+	id := fmt.Sprintf("lint-mcp-%d", t.nextID.Add(1))
+	idJSON, _ := json.Marshal(id)
+	reply := make(chan rpcReply, 1)
+
+	t.pendingMu.Lock()
+	t.pending[string(idJSON)] = reply
+	t.pendingMu.Unlock()
+	defer func() {
+		t.pendingMu.Lock()
+		delete(t.pending, string(idJSON))
+		t.pendingMu.Unlock()
+	}()
+
+	message := map[string]interface{}{"jsonrpc": mcp.JSONRPC_VERSION, "id": id, "method": method}
+	if params != nil {
+		message["params"] = params
+	}
+	if err := t.write(message); err != nil {
+		return nil, fmt.Errorf("发送 %s 请求失败: %v", method, err)
+	}
+
+	select {
+	case r := <-reply:
+		return r.Result, r.Err
+	case <-ctx.Done():
+		return nil, fmt.Errorf("等待客户端响应 %s 超时: %v", method, ctx.Err())
+	}
+}
+
+// resolve 将客户端响应交给对应的等待方
+func (t *stdioTransport) resolve(id string, reply rpcReply) {
+	t.pendingMu.Lock()
+	ch, ok := t.pending[id]
+	t.pendingMu.Unlock()
+	if !ok {
+		log.Printf("收到未知请求 id 的响应: %s", id)
+		return
+	}
+	ch <- reply
+}
+
+// handleSubscription 处理 resources/subscribe 与 resources/unsubscribe
+func (t *stdioTransport) handleSubscription(id json.RawMessage, method string, params json.RawMessage) {
+	var p struct {
+		URI string `json:"uri"`
+	}
+	response := map[string]interface{}{"jsonrpc": mcp.JSONRPC_VERSION, "id": id}
+	if err := json.Unmarshal(params, &p); err != nil || p.URI == "" {
+		response["error"] = map[string]interface{}{"code": mcp.INVALID_PARAMS, "message": "缺少资源 uri"}
+	} else {
+		t.subscriptionsMu.Lock()
+		if method == "resources/subscribe" {
+			t.subscriptions[p.URI] = true
+			log.Printf("客户端订阅资源 %s", p.URI)
+		} else {
+			delete(t.subscriptions, p.URI)
+			log.Printf("客户端取消订阅资源 %s", p.URI)
+		}
+		t.subscriptionsMu.Unlock()
+		response["result"] = map[string]interface{}{}
+	}
+	if err := t.write(response); err != nil {
+		log.Printf("发送响应失败: %v", err)
+	}
+}
+
+// notifyResourceUpdated 资源内容变化时通知订阅了该资源的客户端
+func (t *stdioTransport) notifyResourceUpdated(uri string) {
+	if t == nil {
+		return
+	}
+	t.subscriptionsMu.Lock()
+	subscribed := t.subscriptions[uri]
+	t.subscriptionsMu.Unlock()
+	if !subscribed {
+		return
+	}
+	err := t.write(map[string]interface{}{
+		"jsonrpc": mcp.JSONRPC_VERSION,
+		"method":  "notifications/resources/updated",
+		"params":  map[string]interface{}{"uri": uri},
+	})
+	if err != nil {
+		log.Printf("发送资源更新通知失败: %v", err)
+	}
+}
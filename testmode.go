@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 测试代码的检查范围（code_lint 的 tests 参数）
+const (
+	testsInclude = "include" // 同时检查生产代码与测试代码（默认）
+	testsExclude = "exclude" // 只检查生产代码，golangci-lint 使用 --tests=false
+	testsOnly    = "only"    // 只检查测试代码，仍需加载被测包，结果只保留 _test.go 中的问题
+)
+
+// parseTestsMode 校验 tests 参数，空值按 include 处理
+func parseTestsMode(mode string) (string, error) {
+	switch mode {
+	case "":
+		return testsInclude, nil
+	case testsInclude, testsExclude, testsOnly:
+		return mode, nil
+	}
+	return "", fmt.Errorf("tests 取值无效: %q（可选 include、exclude、only）", mode)
+}
+
+// isTestFile 判断是否为 Go 测试文件
+func isTestFile(path string) bool {
+	return strings.HasSuffix(path, "_test.go")
+}
+
+// testsModeAccepts 判断文件在指定测试范围下是否需要检查
+func testsModeAccepts(mode, path string) bool {
+	switch mode {
+	case testsExclude:
+		return !isTestFile(path)
+	case testsOnly:
+		return isTestFile(path)
+	}
+	return true
+}
+
+// filterFilesByTestsMode 按测试范围过滤文件列表
+func filterFilesByTestsMode(files []string, mode string) []string {
+	if mode == testsInclude {
+		return files
+	}
+	var result []string
+	for _, file := range files {
+		if testsModeAccepts(mode, file) {
+			result = append(result, file)
+		}
+	}
+	return result
+}
+
+// golangciTestsArgs 返回与测试范围对应的 golangci-lint --tests 参数
+// only 模式下测试文件依赖被测包，必须以 --tests=true 加载整个包，再按文件过滤结果
+func golangciTestsArgs(mode string) []string {
+	if mode == testsExclude {
+		return []string{"--tests=false"}
+	}
+	return []string{"--tests=true"}
+}
+
+// filterIssuesByTestsMode 按测试范围过滤检查结果，非文件位置的问题（如工具错误）始终保留
+func filterIssuesByTestsMode(issues []Issue, mode string) []Issue {
+	if mode == testsInclude {
+		return issues
+	}
+	filtered := make([]Issue, 0, len(issues))
+	for _, issue := range issues {
+		if !strings.HasSuffix(issue.Pos.Filename, ".go") || testsModeAccepts(mode, issue.Pos.Filename) {
+			filtered = append(filtered, issue)
+		}
+	}
+	return filtered
+}
+
+// isTestOnlyDir 判断目录是否只包含测试文件（如集成测试目录），这类目录 go build 会报
+// "no non-test Go files"，只能通过 go vet / go test 编译
+func isTestOnlyDir(dir string) bool {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false
+	}
+	hasTest := false
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || filepath.Ext(name) != ".go" {
+			continue
+		}
+		if !isTestFile(name) {
+			return false
+		}
+		hasTest = true
+	}
+	return hasTest
+}
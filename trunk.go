@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"path"
+	"sort"
+	"strings"
+)
+
+// defaultTrunkBranches 未配置主干分支模式时使用的默认主干（origin/HEAD 指向的分支优先）
+var defaultTrunkBranches = []string{"main", "master", "develop"}
+
+// maxTrunkCandidates 参与合并基点计算的主干候选分支上限，避免 release/* 等模式匹配过多分支
+const maxTrunkCandidates = 64
+
+// trunkBranchesDescription 各工具 trunkBranches 参数的说明
+const trunkBranchesDescription = "主干分支模式（可选，如 [\"main\", \"release/*\"]），优先于 .lint-mcp.yaml 中的 trunkBranches。在匹配的分支中选择与 HEAD 合并基点最近者作为基准，结果中的 Scope.Explanation 说明选择依据"
+
+// TrunkBranchesArg 各工具请求结构共用的 trunkBranches 参数，嵌入后字段提升到请求结构
+type TrunkBranchesArg struct {
+	TrunkBranches []string `json:"trunkBranches" description:"主干分支模式（可选，如 [\"main\", \"release/*\"]），优先于 .lint-mcp.yaml 配置"`
+}
+
+// ScopeOptions 变更检测范围选项（来自工具参数）
+type ScopeOptions struct {
+	TrunkBranches []string         // 主干分支模式，优先于配置文件
+	Config        *EffectiveConfig // 生效配置，用于按 exclude 过滤变更文件
+	SkipGenerated bool             // 是否跳过生成代码（代码检查与格式检查默认跳过，编译/测试需要保留）
+}
+
+// ScopeInfo 描述本次检查范围是如何确定的，随检查结果一并返回
+type ScopeInfo struct {
+	RepoBase
+	Repos []RepoBase `json:"Repos,omitempty"` // 子模块与嵌套仓库各自的基准
+}
+
+// trunkChoice 表示选中的最近主干分支
+type trunkChoice struct {
+	Branch     string
+	MergeBase  string
+	Ahead      int // HEAD 相对合并基点领先的提交数
+	Candidates int // 参与比较的候选分支数
+}
+
+// resolveTrunkPatterns 确定主干分支模式及其来源：工具参数 > 项目配置 > 默认值（origin/HEAD + main/master/develop）
+func resolveTrunkPatterns(repo GitRepo, opts ScopeOptions) ([]string, string) {
+	if len(opts.TrunkBranches) > 0 {
+		return opts.TrunkBranches, "工具参数 trunkBranches"
+	}
+	// 优先使用调用方已解析的生效配置，未传入时才从仓库目录加载
+	cfg := opts.Config
+	if cfg == nil {
+		cfg = loadEffectiveConfig(repo.Dir())
+	}
+	if len(cfg.TrunkBranches) > 0 {
+		return cfg.TrunkBranches, "配置文件 " + cfg.FieldSources["trunkBranches"]
+	}
+
+	patterns := append([]string(nil), defaultTrunkBranches...)
+	source := "默认主干 main/master/develop"
+	if remoteBranch, err := repo.DefaultRemoteBranch(); err == nil && remoteBranch != "" {
+		name := strings.TrimPrefix(remoteBranch, "origin/")
+		patterns = append([]string{name}, patterns...)
+		source = fmt.Sprintf("默认主干（origin/HEAD -> %s，及 main/master/develop）", remoteBranch)
+	}
+	return dedupeStrings(patterns), source
+}
+
+// matchTrunkPattern 返回分支匹配的第一个模式下标，不匹配时返回 -1
+// 模式既可匹配去掉远程名的分支名（"release/*" 匹配 "origin/release/1.2"），也可匹配完整短名（"origin/main"）
+func matchTrunkPattern(patterns []string, b GitBranch) int {
+	for i, pattern := range patterns {
+		if ok, _ := path.Match(pattern, b.Name); ok {
+			return i
+		}
+		if ok, _ := path.Match(pattern, b.ShortName()); ok {
+			return i
+		}
+	}
+	return -1
+}
+
+// findClosestTrunk 在匹配主干模式的分支中，找出与 HEAD 合并基点最近（HEAD 领先提交数最少）的分支
+// 当前分支及其远程跟踪分支不参与比较；HEAD 已包含在其中的分支（领先 0 个提交）被跳过。
+// 当前分支本身匹配主干模式时视为位于主干上：相对其上游的未推送提交已由策略1处理，
+// 不再与其他主干（如 develop、release/*）比较，否则会得到范围极大的合并基点
+func findClosestTrunk(repo GitRepo, patterns []string, currentBranch string) (*trunkChoice, error) {
+	if currentBranch != "" && matchTrunkPattern(patterns, GitBranch{Name: currentBranch}) >= 0 {
+		return nil, fmt.Errorf("当前分支 %s 匹配主干分支模式 %v，视为位于主干上，不与其他主干比较", currentBranch, patterns)
+	}
+	branches, err := repo.Branches()
+	if err != nil {
+		return nil, err
+	}
+
+	type candidate struct {
+		branch  GitBranch
+		pattern int
+	}
+	var candidates []candidate
+	for _, b := range branches {
+		if currentBranch != "" && b.Name == currentBranch {
+			continue
+		}
+		if idx := matchTrunkPattern(patterns, b); idx >= 0 {
+			candidates = append(candidates, candidate{branch: b, pattern: idx})
+		}
+	}
+	// 模式顺序优先，其次远程分支优先于本地分支（远程更能代表主干的真实状态）
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].pattern != candidates[j].pattern {
+			return candidates[i].pattern < candidates[j].pattern
+		}
+		if (candidates[i].branch.Remote != "") != (candidates[j].branch.Remote != "") {
+			return candidates[i].branch.Remote != ""
+		}
+		return candidates[i].branch.ShortName() < candidates[j].branch.ShortName()
+	})
+	if len(candidates) > maxTrunkCandidates {
+		log.Printf("⚠️ 主干候选分支 %d 个，仅比较前 %d 个", len(candidates), maxTrunkCandidates)
+		candidates = candidates[:maxTrunkCandidates]
+	}
+
+	var best *trunkChoice
+	for _, c := range candidates {
+		name := c.branch.ShortName()
+		mergeBase, err := repo.MergeBase("HEAD", name)
+		if err != nil {
+			continue
+		}
+		ahead, err := repo.CountCommits(mergeBase, "HEAD")
+		if err != nil || ahead == 0 {
+			continue
+		}
+		if best == nil || ahead < best.Ahead {
+			best = &trunkChoice{Branch: name, MergeBase: mergeBase, Ahead: ahead}
+		}
+	}
+	if best != nil {
+		best.Candidates = len(candidates)
+		log.Printf("✅ 最近主干: %s，合并基点 %s（HEAD 领先 %d 个提交，共比较 %d 个候选）", best.Branch, best.MergeBase, best.Ahead, best.Candidates)
+		return best, nil
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("主干分支模式 %v 未匹配到任何分支", patterns)
+	}
+	return nil, fmt.Errorf("匹配到的 %d 个主干分支均已包含 HEAD", len(candidates))
+}
+
+// dedupeStrings 去除重复项并保持原有顺序
+func dedupeStrings(items []string) []string {
+	seen := make(map[string]bool, len(items))
+	result := make([]string, 0, len(items))
+	for _, item := range items {
+		if item == "" || seen[item] {
+			continue
+		}
+		seen[item] = true
+		result = append(result, item)
+	}
+	return result
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// vulnDBEnv 指定本地漏洞数据库目录的环境变量
+const vulnDBEnv = "LINT_MCP_VULNDB"
+
+// CodeVulnCheckRequest 定义漏洞扫描请求结构
+type CodeVulnCheckRequest struct {
+	Files            []string `json:"files" description:"参考文件列表（可选，用于确定检查起点）"`
+	ProjectPath      string   `json:"projectPath" description:"项目根目录（可选，优先作为检测起点）"`
+	CheckOnlyChanges bool     `json:"checkOnlyChanges" description:"是否只扫描变更文件所属的模块（默认true）" default:"true"`
+	DBPath           string   `json:"dbPath" description:"本地漏洞数据库目录（可选，默认读取环境变量 LINT_MCP_VULNDB）"`
+	TrunkBranchesArg
+}
+
+// VulnCheckResult 表示漏洞扫描结果
+type VulnCheckResult struct {
+	Issues []Issue     `json:"Issues"`
+	Vulns  []VulnEntry `json:"Vulns"`
+	Scope  *ScopeInfo  `json:"Scope,omitempty"`
+}
+
+// VulnEntry 表示一个可达漏洞及其全部调用栈
+type VulnEntry struct {
+	ID           string     `json:"ID"`
+	Aliases      []string   `json:"Aliases"`
+	Summary      string     `json:"Summary"`
+	Module       string     `json:"Module"`
+	FoundVersion string     `json:"FoundVersion"`
+	FixedVersion string     `json:"FixedVersion"`
+	ProjectRoot  string     `json:"ProjectRoot"`
+	CallStacks   [][]string `json:"CallStacks"`
+}
+
+// govulncheckMessage govulncheck -format json 输出流中的单条消息
+type govulncheckMessage struct {
+	OSV *struct {
+		ID      string   `json:"id"`
+		Summary string   `json:"summary"`
+		Details string   `json:"details"`
+		Aliases []string `json:"aliases"`
+	} `json:"osv"`
+	Finding *struct {
+		OSV          string             `json:"osv"`
+		FixedVersion string             `json:"fixed_version"`
+		Trace        []govulncheckFrame `json:"trace"`
+	} `json:"finding"`
+}
+
+// govulncheckFrame 调用栈中的一帧，Trace 从漏洞符号开始，到用户代码入口结束
+type govulncheckFrame struct {
+	Module   string `json:"module"`
+	Version  string `json:"version"`
+	Package  string `json:"package"`
+	Function string `json:"function"`
+	Receiver string `json:"receiver"`
+	Position *struct {
+		Filename string `json:"filename"`
+		Offset   int    `json:"offset"`
+		Line     int    `json:"line"`
+		Column   int    `json:"column"`
+	} `json:"position"`
+}
+
+// checkGovulncheckInstalled 检查govulncheck是否已安装
+func checkGovulncheckInstalled() error {
+	_, err := exec.LookPath("govulncheck")
+	if err != nil {
+		return fmt.Errorf("govulncheck 未安装或不在PATH中")
+	}
+	return nil
+}
+
+// resolveVulnDB 解析本地漏洞数据库目录并转换为 file:// URL
+func resolveVulnDB(dbPath string) (string, error) {
+	if strings.TrimSpace(dbPath) == "" {
+		dbPath = os.Getenv(vulnDBEnv)
+	}
+	if strings.TrimSpace(dbPath) == "" {
+		return "", fmt.Errorf("未配置漏洞数据库：请通过 dbPath 参数或 %s 环境变量指定本地漏洞数据库目录", vulnDBEnv)
+	}
+	abs, err := filepath.Abs(dbPath)
+	if err != nil {
+		return "", fmt.Errorf("dbPath 解析失败: %v", err)
+	}
+	if stat, err := os.Stat(abs); err != nil || !stat.IsDir() {
+		return "", fmt.Errorf("漏洞数据库目录无效或不是目录: %s", abs)
+	}
+	p := filepath.ToSlash(abs)
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p // Windows 盘符路径
+	}
+	return (&url.URL{Scheme: "file", Path: p}).String(), nil
+}
+
+// formatVulnFrame 将调用栈帧格式化为可读文本
+func formatVulnFrame(frame govulncheckFrame, projectRoot string) string {
+	name := frame.Function
+	if frame.Receiver != "" {
+		name = frame.Receiver + "." + name
+	}
+	if frame.Package != "" {
+		name = frame.Package + "." + name
+	}
+	if frame.Position != nil && frame.Position.Filename != "" {
+		filename := frame.Position.Filename
+		if !filepath.IsAbs(filename) && frame.Version == "" {
+			filename = filepath.Join(projectRoot, filename)
+		}
+		return fmt.Sprintf("%s (%s:%d:%d)", name, filename, frame.Position.Line, frame.Position.Column)
+	}
+	return name
+}
+
+// runGovulncheck 在指定模块根目录下运行 govulncheck 并解析可达漏洞
+func runGovulncheck(ctx context.Context, projectRoot, dbURL string, vendorMode bool) ([]Issue, []VulnEntry, error) {
+	args := []string{"-db", dbURL, "-format", "json", "-C", projectRoot, "./..."}
+	log.Printf("执行命令: govulncheck %v", args)
+
+	cmd := exec.CommandContext(ctx, "govulncheck", args...)
+	cmd.Dir = projectRoot
+	cmd.Env = os.Environ()
+	if vendorMode {
+		cmd.Env = append(cmd.Env, "GOFLAGS=-mod=vendor")
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, cmdErr := cmd.Output()
+	log.Printf("命令输出长度: %d", len(output))
+	if cmdErr != nil && len(output) == 0 {
+		return nil, nil, fmt.Errorf("govulncheck 执行失败: %v\n%s", cmdErr, strings.TrimSpace(stderr.String()))
+	}
+
+	osvs := make(map[string]*VulnEntry)
+	var order []string
+	issues := make([]Issue, 0)
+
+	dec := json.NewDecoder(bytes.NewReader(output))
+	for {
+		var msg govulncheckMessage
+		if err := dec.Decode(&msg); err != nil {
+			if err != io.EOF {
+				log.Printf("govulncheck 输出解析中断: %v", err)
+			}
+			break
+		}
+		if msg.OSV != nil {
+			if _, ok := osvs[msg.OSV.ID]; !ok {
+				osvs[msg.OSV.ID] = &VulnEntry{ID: msg.OSV.ID, Aliases: msg.OSV.Aliases, Summary: msg.OSV.Summary, ProjectRoot: projectRoot}
+			}
+			continue
+		}
+		// 只保留符号级（可达）的发现：Trace 首帧包含具体函数
+		if msg.Finding == nil || len(msg.Finding.Trace) == 0 || msg.Finding.Trace[0].Function == "" {
+			continue
+		}
+
+		finding := msg.Finding
+		entry, ok := osvs[finding.OSV]
+		if !ok {
+			entry = &VulnEntry{ID: finding.OSV, ProjectRoot: projectRoot}
+			osvs[finding.OSV] = entry
+		}
+		if len(entry.CallStacks) == 0 {
+			order = append(order, finding.OSV)
+		}
+		vulnFrame := finding.Trace[0]
+		entry.Module = vulnFrame.Module
+		entry.FoundVersion = vulnFrame.Version
+		entry.FixedVersion = finding.FixedVersion
+
+		// 调用栈按 入口 -> 漏洞符号 的顺序展示
+		stack := make([]string, 0, len(finding.Trace))
+		for i := len(finding.Trace) - 1; i >= 0; i-- {
+			stack = append(stack, formatVulnFrame(finding.Trace[i], projectRoot))
+		}
+		entry.CallStacks = append(entry.CallStacks, stack)
+
+		// 定位到用户代码中的入口帧
+		pos := Pos{Filename: projectRoot}
+		for i := len(finding.Trace) - 1; i >= 0; i-- {
+			frame := finding.Trace[i]
+			if frame.Position == nil || frame.Position.Filename == "" {
+				continue
+			}
+			filename := frame.Position.Filename
+			if !filepath.IsAbs(filename) {
+				filename = filepath.Join(projectRoot, filename)
+			}
+			pos = Pos{Filename: filename, Offset: frame.Position.Offset, Line: frame.Position.Line, Column: frame.Position.Column}
+			break
+		}
+
+		fixed := finding.FixedVersion
+		if fixed == "" {
+			fixed = "暂无"
+		}
+		text := fmt.Sprintf("%s: %s\n模块: %s@%s，修复版本: %s\n调用栈:\n  %s",
+			entry.ID, entry.Summary, entry.Module, entry.FoundVersion, fixed, strings.Join(stack, "\n  -> "))
+		issues = append(issues, Issue{
+			FromLinter: "govulncheck",
+			Text:       text,
+			Severity:   "error",
+			Pos:        pos,
+		})
+	}
+
+	vulns := make([]VulnEntry, 0, len(order))
+	for _, id := range order {
+		vulns = append(vulns, *osvs[id])
+	}
+	log.Printf("项目 %s 发现 %d 个可达漏洞，%d 条调用栈", projectRoot, len(vulns), len(issues))
+	return issues, vulns, nil
+}
+
+// handleCodeVulnCheckRequest 处理漏洞扫描请求
+func handleCodeVulnCheckRequest(ctx context.Context, req mcp.CallToolRequest) (result *mcp.CallToolResult, err error) {
+	// 捕获任何 panic 并转换为错误返回
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("发生 panic: %v", r)
+			result = buildErrorResult(fmt.Sprintf("内部错误: %v", r))
+			err = nil
+		}
+	}()
+
+	log.Printf("收到漏洞扫描请求: name=%s args=%v", req.Params.Name, req.Params.Arguments)
+
+	var vulnReq CodeVulnCheckRequest
+	if req.Params.Arguments == nil {
+		req.Params.Arguments = map[string]interface{}{}
+	}
+	cfg := applyToolDefaults("code_vulncheck", req.Params.Arguments)
+	if !cfg.backendEnabled(backendGovulncheck) {
+		return backendDisabledResult(backendGovulncheck, cfg), nil
+	}
+	argsBytes, _ := json.Marshal(req.Params.Arguments)
+	if err := json.Unmarshal(argsBytes, &vulnReq); err != nil {
+		return buildErrorResult(fmt.Sprintf("无效的请求参数: %v", err)), nil
+	}
+	if _, exists := req.Params.Arguments["checkOnlyChanges"]; !exists {
+		vulnReq.CheckOnlyChanges = true
+	}
+
+	if err := checkGovulncheckInstalled(); err != nil {
+		return buildErrorResult(`govulncheck 未安装。请先安装：
+go install golang.org/x/vuln/cmd/govulncheck@latest
+
+安装完成后请确保 govulncheck 在 PATH 环境变量中。`), nil
+	}
+
+	dbURL, err := resolveVulnDB(vulnReq.DBPath)
+	if err != nil {
+		return buildErrorResult(err.Error()), nil
+	}
+
+	baseDir, err := resolveBaseDir(vulnReq.ProjectPath, vulnReq.Files)
+	if err != nil {
+		return buildPathErrorResult(err), nil
+	}
+	log.Printf("漏洞扫描起点目录: %s，数据库: %s", baseDir, dbURL)
+
+	// 确定需要扫描的模块：变更文件所属模块，或起点目录所在模块
+	var projectRoots []string
+	var scope *ScopeInfo
+	if vulnReq.CheckOnlyChanges {
+		changeSet, err := getChangeSet(baseDir, ScopeOptions{TrunkBranches: vulnReq.TrunkBranches, Config: cfg})
+		if err != nil {
+			log.Printf("未检测到变更文件，扫描起点目录所在模块: %v", err)
+		} else {
+			projectPackages := make(map[string][]string)
+			for projectRoot, files := range groupFilesByProject(changeSet.Paths()) {
+				projectPackages[projectRoot] = files
+			}
+			mergeProjectPackages(projectPackages, changeSet.DeletedPackages())
+			scope = changeSet.Scope()
+			for projectRoot := range projectPackages {
+				projectRoots = append(projectRoots, projectRoot)
+			}
+		}
+	} else if vulnReq.ProjectPath == "" {
+		for projectRoot := range groupFilesByProject(vulnReq.Files) {
+			projectRoots = append(projectRoots, projectRoot)
+		}
+	}
+	if len(projectRoots) == 0 {
+		root, err := findGoModRoot(baseDir)
+		if err != nil {
+			return buildErrorResult(fmt.Sprintf("起点目录 %s 不在Go模块内: %v", baseDir, err)), nil
+		}
+		projectRoots = append(projectRoots, root)
+	}
+	sort.Strings(projectRoots)
+
+	vulnResult := &VulnCheckResult{Issues: make([]Issue, 0), Vulns: make([]VulnEntry, 0), Scope: scope}
+	for _, projectRoot := range projectRoots {
+		if _, err := os.Stat(filepath.Join(projectRoot, "go.mod")); err != nil {
+			log.Printf("跳过非Go模块目录: %s", projectRoot)
+			continue
+		}
+		vendorMode := autoDetectVendorMode(projectRoot)
+		issues, vulns, err := runGovulncheck(ctx, projectRoot, dbURL, vendorMode)
+		if err != nil {
+			// 扫描失败是工具自身的错误，与漏洞发现（FromLinter: "govulncheck"）区分
+			vulnResult.Issues = append(vulnResult.Issues, Issue{
+				FromLinter: "lint-mcp",
+				Text:       fmt.Sprintf("项目 %s 漏洞扫描失败: %v", projectRoot, err),
+				Severity:   severityError,
+				Pos:        Pos{Filename: "system"},
+			})
+			continue
+		}
+		vulnResult.Issues = append(vulnResult.Issues, issues...)
+		vulnResult.Vulns = append(vulnResult.Vulns, vulns...)
+	}
+
+	return buildToolResult(vulnResult, cfg), nil
+}
@@ -0,0 +1,592 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// watchEnv 设置为 1/true 时启用监听模式（等价于命令行参数 -watch）
+const watchEnv = "LINT_MCP_WATCH"
+
+const (
+	// liveResourceURI 监听模式维护的实时问题集
+	liveResourceURI = "lint://live"
+	// watchDebounce 文件变更后等待的静默时间，连续保存只触发一次检查
+	watchDebounce = 500 * time.Millisecond
+)
+
+// liveWorkspace 监听模式：监听工作区内的项目，文件变更后在后台只重新检查受影响的包，维护实时问题集
+type liveWorkspace struct {
+	mu       sync.Mutex
+	enabled  bool
+	projects map[string]*liveProject // 监听根目录 -> 项目
+}
+
+// live 全局监听状态
+var live = &liveWorkspace{projects: make(map[string]*liveProject)}
+
+// liveProject 单个被监听的项目（Git 仓库或 Go 模块目录，可包含多个模块）
+type liveProject struct {
+	root    string
+	watcher *fsnotify.Watcher
+
+	mu        sync.Mutex
+	issues    map[string][]Issue // 包目录 -> 问题（文件名与常规检查一致，相对模块根目录）
+	ready     bool               // 首次全量检查已完成
+	full      bool               // 待全量检查（首次检查或 go.mod、lint 配置变更）
+	pending   map[string]bool    // 待重新检查的包目录
+	running   bool
+	idle      bool // 没有待检查或进行中的检查
+	timer     *time.Timer
+	closed    bool
+	updatedAt time.Time
+	lastError string
+}
+
+// LiveInfo code_lint 结果来自监听模式的实时问题集时的说明
+type LiveInfo struct {
+	Root      string `json:"Root"`
+	UpdatedAt string `json:"UpdatedAt"`
+	Pending   bool   `json:"Pending,omitempty"` // 有待检查或进行中的后台检查，结果可能尚未反映最近的变更
+}
+
+// LiveResource lint://live 的内容
+type LiveResource struct {
+	Enabled  bool               `json:"Enabled"`
+	Projects []LiveProjectState `json:"Projects"`
+}
+
+// LiveProjectState 单个被监听项目的状态与当前问题
+type LiveProjectState struct {
+	Root      string  `json:"Root"`
+	Ready     bool    `json:"Ready"`
+	Pending   bool    `json:"Pending"` // 有待检查或进行中的后台检查
+	UpdatedAt string  `json:"UpdatedAt,omitempty"`
+	Error     string  `json:"Error,omitempty"`
+	Issues    []Issue `json:"Issues"`
+}
+
+// initWatch 按命令行参数 -watch 或环境变量 LINT_MCP_WATCH 启用监听模式；
+// 已配置工作区白名单时立即监听其中的项目，否则等待客户端 roots
+func initWatch(flagValue bool) {
+	env := strings.ToLower(strings.TrimSpace(os.Getenv(watchEnv)))
+	enabled := flagValue || env == "1" || env == "true"
+	live.mu.Lock()
+	live.enabled = enabled
+	live.mu.Unlock()
+	if !enabled {
+		return
+	}
+	log.Printf("已启用监听模式，文件变更后在后台重新检查受影响的包")
+	if roots, _ := allowedRoots.List(); len(roots) > 0 {
+		go live.sync(defaultLintTargets(roots))
+	}
+}
+
+// isEnabled 是否启用了监听模式
+func (w *liveWorkspace) isEnabled() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.enabled
+}
+
+// sync 监听给定的项目，并停止监听已不在工作区白名单内的项目（如客户端 roots 变更后）
+func (w *liveWorkspace) sync(targets []string) {
+	if !w.isEnabled() {
+		return
+	}
+	w.mu.Lock()
+	for root, p := range w.projects {
+		if allowedRoots.Check(root) != nil {
+			log.Printf("项目 %s 已不在工作区内，停止监听", root)
+			p.close()
+			delete(w.projects, root)
+		}
+	}
+	w.mu.Unlock()
+	for _, target := range targets {
+		w.ensure(target)
+	}
+}
+
+// ensure 开始监听项目（已被监听的项目或其子目录直接返回）
+func (w *liveWorkspace) ensure(root string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.enabled {
+		return
+	}
+	for existing := range w.projects {
+		if isWithinDir(existing, root) {
+			return
+		}
+	}
+	p, err := newLiveProject(root)
+	if err != nil {
+		log.Printf("监听项目 %s 失败: %v", root, err)
+		return
+	}
+	// 新项目包含已监听的子目录时，由新项目接管
+	for existing, old := range w.projects {
+		if isWithinDir(root, existing) {
+			old.close()
+			delete(w.projects, existing)
+		}
+	}
+	w.projects[root] = p
+	log.Printf("开始监听项目: %s", root)
+	go p.loop()
+	p.schedule(0)
+}
+
+// lookup 返回包含该目录的被监听项目
+func (w *liveWorkspace) lookup(dir string) *liveProject {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for root, p := range w.projects {
+		if isWithinDir(root, dir) {
+			return p
+		}
+	}
+	return nil
+}
+
+// watchRootFor 按请求目录确定监听范围：所在 Git 仓库，其次所在 Go 模块；超出工作区白名单时只监听该目录
+func watchRootFor(dir string) string {
+	root := dir
+	if top, err := newGitRepo(dir).TopLevel(); err == nil {
+		root = top
+	} else if modRoot, err := findGoModRoot(dir); err == nil {
+		root = modRoot
+	}
+	if allowedRoots.Check(root) != nil {
+		return dir
+	}
+	return root
+}
+
+func newLiveProject(root string) (*liveProject, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	p := &liveProject{
+		root:    root,
+		watcher: watcher,
+		issues:  make(map[string][]Issue),
+		full:    true,
+		pending: make(map[string]bool),
+	}
+	p.addTree(root)
+	return p, nil
+}
+
+// addTree 监听目录及其子目录（fsnotify 不支持递归监听，跳过 isSkippedWalkDir 排除的目录），返回其中包含 Go 文件的目录
+func (p *liveProject) addTree(dir string) []string {
+	var goDirs []string
+	_ = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.IsDir() {
+			return nil
+		}
+		if path != p.root && isSkippedWalkDir(info.Name()) {
+			return filepath.SkipDir
+		}
+		if err := p.watcher.Add(path); err != nil {
+			// 常见原因是超出 fs.inotify.max_user_watches
+			log.Printf("监听目录 %s 失败: %v", path, err)
+		}
+		if matches, _ := filepath.Glob(filepath.Join(path, "*.go")); len(matches) > 0 {
+			goDirs = append(goDirs, path)
+		}
+		return nil
+	})
+	return goDirs
+}
+
+// close 停止监听
+func (p *liveProject) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	if p.timer != nil {
+		p.timer.Stop()
+	}
+	_ = p.watcher.Close()
+}
+
+// loop 处理文件系统事件，直到停止监听
+func (p *liveProject) loop() {
+	for {
+		select {
+		case event, ok := <-p.watcher.Events:
+			if !ok {
+				return
+			}
+			p.handleEvent(event)
+		case err, ok := <-p.watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("监听项目 %s 出错: %v", p.root, err)
+		}
+	}
+}
+
+// handleEvent 按变更的文件确定需要重新检查的范围
+func (p *liveProject) handleEvent(event fsnotify.Event) {
+	if event.Op == fsnotify.Chmod {
+		return
+	}
+	rel, err := filepath.Rel(p.root, event.Name)
+	if err != nil {
+		return
+	}
+	for _, part := range strings.Split(filepath.Dir(rel), string(filepath.Separator)) {
+		if part != "." && isSkippedWalkDir(part) {
+			return
+		}
+	}
+	name := filepath.Base(event.Name)
+
+	if event.Op&fsnotify.Create != 0 {
+		if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+			if !isSkippedWalkDir(name) {
+				p.markDirty(false, p.addTree(event.Name)...)
+			}
+			return
+		}
+	}
+
+	switch {
+	case name == "go.mod" || name == "go.sum" || name == "go.work" || strings.HasPrefix(name, ".golangci") || containsString(projectConfigNames, name):
+		log.Printf("%s 已变更，重新检查整个项目 %s", event.Name, p.root)
+		p.markDirty(true)
+	case strings.HasSuffix(name, ".go"):
+		p.markDirty(false, filepath.Dir(event.Name))
+	case event.Op&(fsnotify.Remove|fsnotify.Rename) != 0:
+		// 删除或移走的目录：其中的包需要移除
+		p.mu.Lock()
+		var dirs []string
+		for dir := range p.issues {
+			if isWithinDir(event.Name, dir) {
+				dirs = append(dirs, dir)
+			}
+		}
+		p.mu.Unlock()
+		if len(dirs) > 0 {
+			p.markDirty(false, dirs...)
+		}
+	}
+}
+
+// markDirty 记录待重新检查的包目录（full=true 表示整个项目），并重新开始防抖计时
+func (p *liveProject) markDirty(full bool, dirs ...string) {
+	p.mu.Lock()
+	if full {
+		p.full = true
+	}
+	for _, dir := range dirs {
+		p.pending[dir] = true
+	}
+	p.mu.Unlock()
+	p.schedule(watchDebounce)
+}
+
+// schedule 在 delay 后执行后台检查；进行中的检查结束后会处理期间新增的变更
+func (p *liveProject) schedule(delay time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return
+	}
+	p.idle = false
+	if p.timer == nil {
+		p.timer = time.AfterFunc(delay, p.relint)
+		return
+	}
+	p.timer.Reset(delay)
+}
+
+// relint 重新检查待检查的包（或整个项目），更新问题集，有变化时通知订阅了 lint://live 的客户端
+func (p *liveProject) relint() {
+	p.mu.Lock()
+	if p.running || p.closed {
+		p.mu.Unlock()
+		return
+	}
+	full := p.full || !p.ready
+	var dirs []string
+	if !full {
+		for dir := range p.pending {
+			dirs = append(dirs, dir)
+		}
+	}
+	p.full = false
+	p.pending = make(map[string]bool)
+	p.running = true
+	p.mu.Unlock()
+
+	start := time.Now()
+	issues, checked, err := p.lint(full, dirs)
+
+	p.mu.Lock()
+	p.running = false
+	changed := false
+	if err != nil {
+		log.Printf("后台检查项目 %s 失败: %v", p.root, err)
+		p.lastError = err.Error()
+	} else {
+		if full {
+			changed = !sameIssueSets(p.issues, issues)
+			p.issues = issues
+		} else {
+			for _, dir := range checked {
+				if !sameIssues(p.issues[dir], issues[dir]) {
+					changed = true
+				}
+				if len(issues[dir]) == 0 {
+					delete(p.issues, dir)
+				} else {
+					p.issues[dir] = issues[dir]
+				}
+			}
+		}
+		changed = changed || !p.ready
+		p.ready = true
+		p.lastError = ""
+		p.updatedAt = time.Now()
+		log.Printf("后台检查项目 %s 完成（全量: %v，包: %d），耗时 %v，问题有变化: %v", p.root, full, len(checked), time.Since(start).Round(time.Millisecond), changed)
+	}
+	if (p.full || len(p.pending) > 0) && !p.closed {
+		p.timer.Reset(watchDebounce)
+	} else {
+		p.idle = true
+	}
+	p.mu.Unlock()
+
+	if changed {
+		transport.notifyResourceUpdated(liveResourceURI)
+	}
+}
+
+// lint 检查整个项目或指定的包目录，返回按包目录分组的问题以及实际检查的包目录。
+// 只检查部分包时，模块内（直接或间接）导入了这些包的其他包一并重新检查，
+// 以便发现导出 API 变更在调用处引起的类型错误
+func (p *liveProject) lint(full bool, dirs []string) (map[string][]Issue, []string, error) {
+	cfg := loadEffectiveConfig(p.root)
+	var files []string
+	if full {
+		all, _, err := findAllGoFiles(p.root, cfg, false, testsInclude)
+		if err != nil {
+			log.Printf("项目 %s 中没有可检查的 Go 文件: %v", p.root, err)
+			return map[string][]Issue{}, nil, nil
+		}
+		files = all
+	} else {
+		for _, dir := range dirs {
+			matches, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+			for _, file := range matches {
+				if !cfg.isExcluded(file) && !isGeneratedGoFile(file, cfg) {
+					files = append(files, file)
+				}
+			}
+		}
+	}
+
+	issues := make(map[string][]Issue)
+	checked := append([]string(nil), dirs...)
+	if len(files) == 0 {
+		return issues, checked, nil
+	}
+	projectPackages, err := getPackagesFromFiles(files)
+	if err != nil {
+		return nil, nil, err
+	}
+	// 后台检查不属于任何请求，不随请求取消
+	ctx := context.Background()
+	for projectRoot, packages := range projectPackages {
+		vendorMode := autoDetectVendorMode(projectRoot)
+		if !full {
+			dependents, err := expandDependents(ctx, projectRoot, packages, 0, vendorMode)
+			if err != nil {
+				log.Printf("查找依赖包失败（项目: %s）: %v", projectRoot, err)
+			}
+			for _, dep := range dependents {
+				packages = append(packages, dep.Package)
+				checked = append(checked, filepath.Join(projectRoot, filepath.FromSlash(dep.Package)))
+			}
+		}
+		sort.Strings(packages)
+		result, err := runGolangciLint(ctx, projectRoot, packages, "package", false, vendorMode, testsInclude, BuildConfig{}, cfg.Limits)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, issue := range result.Issues {
+			dir := filepath.Dir(issueAbsPath(projectRoot, issue))
+			issues[dir] = append(issues[dir], issue)
+		}
+	}
+	return issues, checked, nil
+}
+
+// sameIssues 比较两组问题是否相同（忽略顺序）
+func sameIssues(a, b []Issue) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	counts := make(map[string]int, len(a))
+	for _, issue := range a {
+		counts[issueKey(issue)]++
+	}
+	for _, issue := range b {
+		key := issueKey(issue)
+		if counts[key] == 0 {
+			return false
+		}
+		counts[key]--
+	}
+	return true
+}
+
+// sameIssueSets 比较两个按包目录分组的问题集是否相同
+func sameIssueSets(a, b map[string][]Issue) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for dir, issues := range a {
+		if !sameIssues(issues, b[dir]) {
+			return false
+		}
+	}
+	return true
+}
+
+// state 返回项目状态；dir 非空时只包含该目录下的问题
+func (p *liveProject) state(dir string) LiveProjectState {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	st := LiveProjectState{Root: p.root, Ready: p.ready, Pending: !p.idle, Error: p.lastError, Issues: []Issue{}}
+	if !p.updatedAt.IsZero() {
+		st.UpdatedAt = p.updatedAt.Format(time.RFC3339)
+	}
+	var pkgDirs []string
+	for pkgDir := range p.issues {
+		if dir == "" || isWithinDir(dir, pkgDir) {
+			pkgDirs = append(pkgDirs, pkgDir)
+		}
+	}
+	sort.Strings(pkgDirs)
+	for _, pkgDir := range pkgDirs {
+		st.Issues = append(st.Issues, p.issues[pkgDir]...)
+	}
+	return st
+}
+
+// serve 监听模式下用实时问题集直接响应 code_lint。
+// 只支持默认检查选项（测试范围 include、不含生成代码与依赖包、默认构建配置），其他情况返回 false 按常规方式检查；
+// 请求的目录尚未被监听时开始监听，本次仍按常规方式检查
+func (w *liveWorkspace) serve(baseDir string, lintReq CodeLintRequest, tests string, buildMatrix []BuildConfig, cfg *EffectiveConfig) (*LintResult, bool) {
+	if !w.isEnabled() {
+		return nil, false
+	}
+	if lintReq.IncludeDependents || lintReq.IncludeGenerated || tests != testsInclude || len(buildMatrix) != 1 || !buildMatrix[0].isDefault() {
+		log.Printf("请求使用了非默认的检查选项，不使用监听模式的实时结果")
+		return nil, false
+	}
+	p := w.lookup(baseDir)
+	if p == nil {
+		w.ensure(watchRootFor(baseDir))
+		return nil, false
+	}
+	// 不等待进行中的后台检查，直接返回当前问题集并标记 Pending
+	st := p.state(baseDir)
+	if !st.Ready {
+		log.Printf("项目 %s 的实时问题集不可用（%s），按常规方式检查", p.root, st.Error)
+		return nil, false
+	}
+
+	result := &LintResult{Issues: st.Issues, Live: &LiveInfo{Root: st.Root, UpdatedAt: st.UpdatedAt, Pending: st.Pending}}
+	if lintReq.CheckOnlyChanges {
+		changeSet, err := getChangeSet(baseDir, ScopeOptions{TrunkBranches: lintReq.TrunkBranches, Config: cfg, SkipGenerated: true})
+		if err != nil {
+			// 与常规检查一致：无法确定变更范围时检查全部文件
+			log.Printf("Git检测失败（起点: %s），返回全部实时问题: %v", baseDir, err)
+			return result, true
+		}
+		wanted := make(map[string]bool)
+		for _, path := range changeSet.Paths() {
+			wanted[path] = true
+		}
+		deletedDirs := make(map[string]bool)
+		for projectRoot, packages := range changeSet.DeletedPackages() {
+			for _, pkg := range packages {
+				deletedDirs[filepath.Join(projectRoot, pkg)] = true
+			}
+		}
+		filtered := make([]Issue, 0)
+		for _, issue := range result.Issues {
+			path := issueAbsPath(p.root, issue)
+			if wanted[path] || deletedDirs[filepath.Dir(path)] {
+				filtered = append(filtered, issue)
+			}
+		}
+		result.Issues = changeSet.FilterIssues(baseDir, filtered)
+		result.Scope = changeSet.Scope()
+		result.SkippedGenerated = len(changeSet.SkippedGenerated)
+	} else if len(lintReq.Files) > 0 {
+		wanted := make(map[string]bool)
+		for _, file := range lintReq.Files {
+			if abs, err := filepath.Abs(file); err == nil {
+				wanted[abs] = true
+			}
+		}
+		filtered := make([]Issue, 0)
+		for _, issue := range result.Issues {
+			if wanted[issueAbsPath(p.root, issue)] {
+				filtered = append(filtered, issue)
+			}
+		}
+		result.Issues = filtered
+	}
+	result.Issues = dedupeIssues(result.Issues)
+	log.Printf("使用监听模式的实时问题集响应（项目: %s，更新于 %s，后台检查进行中: %v），%d 个问题", st.Root, st.UpdatedAt, st.Pending, len(result.Issues))
+	return result, true
+}
+
+// registerLiveResource 注册 lint://live 资源，客户端可通过 resources/subscribe 订阅其变更
+func registerLiveResource(s *server.MCPServer) {
+	s.AddResource(mcp.NewResource(liveResourceURI, "live-issues",
+		mcp.WithResourceDescription("监听模式（-watch）下各项目的实时问题集；问题变化时向订阅者发送 notifications/resources/updated"),
+		mcp.WithMIMEType("application/json"),
+	), handleLiveResource)
+}
+
+func handleLiveResource(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	res := LiveResource{Enabled: live.isEnabled(), Projects: []LiveProjectState{}}
+	live.mu.Lock()
+	projects := make([]*liveProject, 0, len(live.projects))
+	for _, p := range live.projects {
+		projects = append(projects, p)
+	}
+	live.mu.Unlock()
+	sort.Slice(projects, func(i, j int) bool { return projects[i].root < projects[j].root })
+	for _, p := range projects {
+		res.Projects = append(res.Projects, p.state(""))
+	}
+	data, err := json.Marshal(res)
+	if err != nil {
+		return nil, err
+	}
+	return []mcp.ResourceContents{mcp.TextResourceContents{URI: req.Params.URI, MIMEType: "application/json", Text: string(data)}}, nil
+}
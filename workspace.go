@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// allowedRootsEnv 允许访问的工作区根目录列表（以系统路径分隔符分隔，Linux/macOS 为 ":"）
+const allowedRootsEnv = "LINT_MCP_ALLOWED_ROOTS"
+
+// rootListFlag 可重复的 -allow-root 命令行参数
+type rootListFlag []string
+
+func (f *rootListFlag) String() string {
+	return strings.Join(*f, string(os.PathListSeparator))
+}
+
+func (f *rootListFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// workspaceRoots 工作区根目录白名单，来源依次为命令行参数、环境变量、客户端 roots/list。
+// 都未提供时不做限制（兼容单用户本地使用）；否则所有 projectPath、files 以及推断出的项目根目录都必须在某个根目录之内（按符号链接解析后的真实路径判断）
+type workspaceRoots struct {
+	mu     sync.RWMutex
+	roots  []string // 已解析符号链接的绝对路径
+	source string   // 白名单来源，用于错误信息
+	// configured 白名单来自命令行或环境变量，此时忽略客户端 roots（客户端不能放宽服务端配置）
+	configured bool
+}
+
+// allowedRoots 全局工作区白名单
+var allowedRoots = &workspaceRoots{}
+
+// WorkspaceViolationError 路径不在允许的工作区内
+type WorkspaceViolationError struct {
+	Path         string   `json:"Path"`
+	ResolvedPath string   `json:"ResolvedPath"`
+	AllowedRoots []string `json:"AllowedRoots"`
+	Source       string   `json:"Source"`
+}
+
+func (e *WorkspaceViolationError) Error() string {
+	resolved := ""
+	if e.ResolvedPath != e.Path {
+		resolved = fmt.Sprintf("（解析为 %s）", e.ResolvedPath)
+	}
+	return fmt.Sprintf("路径 %s%s 不在允许的工作区内，允许的根目录（来源: %s）: %s", e.Path, resolved, e.Source, strings.Join(e.AllowedRoots, ", "))
+}
+
+// Set 替换白名单；每个根目录必须存在，按符号链接解析后保存
+func (w *workspaceRoots) Set(roots []string, source string) error {
+	var resolved []string
+	for _, root := range roots {
+		root = strings.TrimSpace(root)
+		if root == "" {
+			continue
+		}
+		if !filepath.IsAbs(root) {
+			return fmt.Errorf("工作区根目录必须是绝对路径: %s", root)
+		}
+		real, err := filepath.EvalSymlinks(root)
+		if err != nil {
+			return fmt.Errorf("工作区根目录无效 %s: %v", root, err)
+		}
+		resolved = append(resolved, filepath.Clean(real))
+	}
+	resolved = dedupeStrings(resolved)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.roots = resolved
+	w.source = source
+	log.Printf("工作区白名单（来源: %s）: %v", source, resolved)
+	return nil
+}
+
+// SetClientRoots 使用客户端 roots 作为白名单（服务端已配置白名单时忽略）
+func (w *workspaceRoots) SetClientRoots(roots []string) {
+	w.mu.RLock()
+	configured := w.configured
+	w.mu.RUnlock()
+	if configured {
+		log.Printf("已配置工作区白名单，忽略客户端 roots 作为白名单")
+		return
+	}
+	if err := w.Set(roots, "客户端 roots/list"); err != nil {
+		log.Printf("客户端 roots 无法作为工作区白名单: %v", err)
+	}
+}
+
+// List 返回当前白名单
+func (w *workspaceRoots) List() ([]string, string) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return append([]string(nil), w.roots...), w.source
+}
+
+// Check 校验路径是否位于白名单内；白名单为空时不做限制
+func (w *workspaceRoots) Check(path string) error {
+	roots, source := w.List()
+	if len(roots) == 0 {
+		return nil
+	}
+	resolved := resolveRealPath(path)
+	for _, root := range roots {
+		if isWithinDir(root, resolved) {
+			return nil
+		}
+	}
+	return &WorkspaceViolationError{Path: path, ResolvedPath: resolved, AllowedRoots: roots, Source: source}
+}
+
+// resolveRealPath 解析路径中的符号链接；路径不存在时（如已删除的文件）解析最近的已存在上级目录再拼接剩余部分
+func resolveRealPath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.Clean(path)
+	}
+	var rest []string
+	current := abs
+	for {
+		if real, err := filepath.EvalSymlinks(current); err == nil {
+			return filepath.Join(append([]string{real}, rest...)...)
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return abs
+		}
+		rest = append([]string{filepath.Base(current)}, rest...)
+		current = parent
+	}
+}
+
+// initAllowedRoots 按命令行参数 -allow-root 与环境变量 LINT_MCP_ALLOWED_ROOTS 初始化白名单（命令行优先），二者均未设置时等待客户端 roots
+func initAllowedRoots(flagRoots []string) error {
+	var err error
+	switch env := os.Getenv(allowedRootsEnv); {
+	case len(flagRoots) > 0:
+		err = allowedRoots.Set(flagRoots, "命令行参数 -allow-root")
+	case env != "":
+		err = allowedRoots.Set(filepath.SplitList(env), "环境变量 "+allowedRootsEnv)
+	default:
+		log.Printf("未配置工作区白名单（-allow-root 或 %s），将使用客户端 roots；客户端未提供时不限制可访问的路径", allowedRootsEnv)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	allowedRoots.mu.Lock()
+	allowedRoots.configured = true
+	allowedRoots.mu.Unlock()
+	return nil
+}
+
+// checkWorkspacePaths 校验请求中的 projectPath、files 及推断出的项目根目录
+func checkWorkspacePaths(projectPath string, files []string, baseDir string) error {
+	var paths []string
+	if strings.TrimSpace(projectPath) != "" {
+		paths = append(paths, projectPath)
+	}
+	for _, file := range files {
+		if strings.TrimSpace(file) != "" {
+			paths = append(paths, file)
+		}
+	}
+	paths = append(paths, baseDir)
+	// 白名单可能来自客户端 roots，首次获取完成前不做判断
+	clientRoots.waitReady()
+	for _, path := range paths {
+		if err := allowedRoots.Check(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// buildPathErrorResult 构造起点目录解析失败的结果；越出工作区的路径附带结构化的 WorkspaceViolation
+func buildPathErrorResult(err error) *mcp.CallToolResult {
+	var violation *WorkspaceViolationError
+	if !errors.As(err, &violation) {
+		return buildErrorResult(err.Error())
+	}
+	log.Printf("拒绝访问工作区之外的路径: %v", err)
+	lr := &LintResult{
+		Issues: []Issue{{
+			FromLinter: "lint-mcp",
+			Text:       err.Error(),
+			Severity:   "error",
+			Pos:        Pos{Filename: violation.Path},
+		}},
+		WorkspaceViolation: violation,
+	}
+	b, _ := json.Marshal(lr)
+	return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Type: "text", Text: string(b)}}}
+}